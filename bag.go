@@ -0,0 +1,234 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+// A Bag is a multiset that stores each distinct item once with its count, instead of a slice entry for every copy like EqualSet. Unlike EqualSet the Add and Remove methods modify the receiver; the multiset operations return a new Bag. The zero value is an empty bag.
+//
+// Hashable items are found in a hash bucket, other items by comparing against every distinct non-hashable item.
+type Bag struct {
+	entries   []bagEntry
+	positions index
+	total     int
+}
+
+type bagEntry struct {
+	item  Comparable
+	count int
+}
+
+// Creates a Bag with the items of the set. Converting back with Bag.EqualSet gives a set Equal to the argument.
+func NewBag(from EqualSet) *Bag {
+	out := &Bag{}
+	for _, item := range from {
+		out.Add(item, 1)
+	}
+	return out
+}
+
+// Adds n copies of the item.
+func (a *Bag) Add(the Comparable, n int) {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil arg")
+		}
+		if n < 0 {
+			panic("unordered: negative count")
+		}
+	}
+	if n == 0 {
+		return
+	}
+	i, found := a.find(the)
+	if found == false {
+		i = a.insert(the)
+	}
+	a.entries[i].count += n
+	a.total += n
+}
+
+// Removes up to n copies of the item and returns the count removed.
+func (a *Bag) Remove(the Comparable, n int) int {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil arg")
+		}
+		if n < 0 {
+			panic("unordered: negative count")
+		}
+	}
+	i, found := a.find(the)
+	if found == false {
+		return 0
+	}
+	if n >= a.entries[i].count {
+		n = a.entries[i].count
+		a.delete(i)
+	} else {
+		a.entries[i].count -= n
+	}
+	a.total -= n
+	return n
+}
+
+// Removes all copies of the item and returns the count removed.
+func (a *Bag) RemoveAll(the Comparable) int {
+	return a.Remove(the, a.Count(the))
+}
+
+// Returns the count of copies of the item.
+func (a *Bag) Count(the Comparable) int {
+	i, found := a.find(the)
+	if found == false {
+		return 0
+	}
+	return a.entries[i].count
+}
+
+// If the bag has a copy of the item then true is returned.
+func (a *Bag) Has(the Comparable) bool {
+	_, found := a.find(the)
+	return found
+}
+
+// Returns the count of distinct items.
+func (a *Bag) Distinct() int {
+	return len(a.entries)
+}
+
+// Returns the count of all copies of all items, the length of the equivalent EqualSet.
+func (a *Bag) Total() int {
+	return a.total
+}
+
+// Calls the function with each distinct item and its count, in no particular order, until it returns false. The bag must not be modified during Each.
+func (a *Bag) Each(fn func(item Comparable, count int) bool) {
+	for _, e := range a.entries {
+		if fn(e.item, e.count) == false {
+			return
+		}
+	}
+}
+
+// Returns an EqualSet with count copies of each item.
+func (a *Bag) EqualSet() EqualSet {
+	out := make(EqualSet, 0, a.total)
+	for _, e := range a.entries {
+		for i := 0; i < e.count; i++ {
+			out = append(out, e.item)
+		}
+	}
+	return out
+}
+
+// Returns a copy of the bag.
+func (a *Bag) Copy() *Bag {
+	out := &Bag{}
+	for _, e := range a.entries {
+		out.Add(e.item, e.count)
+	}
+	return out
+}
+
+// If both bags contain an equal count of each item then true is returned, like EqualSet.Equal.
+func (a *Bag) Equal(to *Bag) bool {
+	if (len(a.entries) != len(to.entries)) || (a.total != to.total) {
+		return false
+	}
+	for _, e := range a.entries {
+		if to.Count(e.item) != e.count {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a bag with the greater count of each item in either bag.
+func (a *Bag) Union(with *Bag) *Bag {
+	return a.combine(with, func(x, y int) int {
+		if x > y {
+			return x
+		}
+		return y
+	})
+}
+
+// Returns a bag with the lesser count of each item in either bag.
+func (a *Bag) Intersection(with *Bag) *Bag {
+	return a.combine(with, func(x, y int) int {
+		if x < y {
+			return x
+		}
+		return y
+	})
+}
+
+// Returns a bag with the sum of the counts of each item in both bags, like EqualSet.Combine.
+func (a *Bag) Sum(with *Bag) *Bag {
+	return a.combine(with, func(x, y int) int { return x + y })
+}
+
+// Returns a bag with the count of each item in the receiver less its count in the argument bag, where that's more than zero.
+func (a *Bag) Subtract(the *Bag) *Bag {
+	return a.combine(the, func(x, y int) int { return x - y })
+}
+
+// Returns a bag with the difference between the counts of each item in both bags, the copies not matched in the other bag. Unlike EqualSet.Diff an item in both bags is kept if its counts differ.
+func (a *Bag) SymmetricDifference(from *Bag) *Bag {
+	return a.combine(from, func(x, y int) int {
+		if x > y {
+			return x - y
+		}
+		return y - x
+	})
+}
+
+// Calls the function with the counts in both bags of each distinct item, and returns a bag of the positive results.
+func (a *Bag) combine(with *Bag, fn func(x, y int) int) *Bag {
+	out := &Bag{}
+	for _, e := range a.entries {
+		out.Add(e.item, positive(fn(e.count, with.Count(e.item))))
+	}
+	for _, e := range with.entries {
+		if a.Has(e.item) {
+			continue
+		}
+		out.Add(e.item, positive(fn(0, e.count)))
+	}
+	return out
+}
+
+func positive(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+func (a *Bag) find(the Comparable) (int, bool) {
+	return a.positions.find(the)
+}
+
+func (a *Bag) insert(the Comparable) int {
+	i := len(a.entries)
+	a.entries = append(a.entries, bagEntry{item: the})
+	a.positions.addAt(the, i)
+	return i
+}
+
+// Removes the entry by moving the last entry into its place.
+func (a *Bag) delete(i int) {
+	a.positions.remove(a.entries[i].item)
+	last := len(a.entries) - 1
+	if i != last {
+		a.positions.move(a.entries[last].item, i)
+		a.entries[i] = a.entries[last]
+	}
+	a.entries[last] = bagEntry{}
+	a.entries = a.entries[:last]
+}
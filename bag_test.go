@@ -0,0 +1,96 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBag(t *testing.T) {
+	r := rand.New(rand.NewSource(8))
+	for _, item := range []func(int) Comparable{
+		func(i int) Comparable { return Int(i) },
+		func(i int) Comparable { return Collider(i) },
+		func(i int) Comparable { return Coordinate{i, 0} },
+	} {
+		var bag Bag
+		expected := EqualSet{}
+		for i := 0; i < 3000; i++ {
+			c := item(r.Intn(100))
+			n := r.Intn(4)
+			switch r.Intn(3) {
+			case 0:
+				bag.Add(c, n)
+				for j := 0; j < n; j++ {
+					expected = append(expected, c)
+				}
+			case 1:
+				removed := 0
+				for ; removed < n; removed++ {
+					var ok bool
+					expected, ok = removeOne(expected, c)
+					if ok == false {
+						break
+					}
+				}
+				if bag.Remove(c, n) != removed {
+					t.Fatalf("%T: removed wrong count of %v", c, c)
+				}
+			case 2:
+				var count int
+				expected, count = removeAll(expected, c)
+				if bag.RemoveAll(c) != count {
+					t.Fatalf("%T: RemoveAll removed wrong count of %v", c, c)
+				}
+			}
+			if (bag.Total() != len(expected)) || (bag.Has(c) != expected.Has(c)) {
+				t.Fatalf("%T: bag has %v items, expected %v", c, bag.Total(), len(expected))
+			}
+		}
+		if (bag.EqualSet().Equal(expected) == false) || (bag.Distinct() != len(expected.Reduce())) {
+			t.Fatalf("%T: bag differs from model", item(0))
+		}
+		if NewBag(expected).Equal(&bag) == false {
+			t.Fatalf("%T: converted bag isn't Equal", item(0))
+		}
+	}
+}
+
+func TestBagOperations(t *testing.T) {
+	a := NewBag(EqualSet{Int(1), Int(1), Int(1), Int(2), Int(5)})
+	b := NewBag(EqualSet{Int(1), Int(2), Int(2), Int(3), Int(5), Int(5)})
+	for _, c := range []struct {
+		name     string
+		result   *Bag
+		expected EqualSet
+	}{
+		{"Union", a.Union(b), EqualSet{Int(1), Int(1), Int(1), Int(2), Int(2), Int(3), Int(5), Int(5)}},
+		{"Intersection", a.Intersection(b), EqualSet{Int(1), Int(2), Int(5)}},
+		{"Sum", a.Sum(b), EqualSet{Int(1), Int(1), Int(1), Int(1), Int(2), Int(2), Int(2), Int(3), Int(5), Int(5), Int(5)}},
+		{"Subtract", a.Subtract(b), EqualSet{Int(1), Int(1)}},
+		{"Subtract", b.Subtract(a), EqualSet{Int(2), Int(3), Int(5)}},
+		{"SymmetricDifference", a.SymmetricDifference(b), EqualSet{Int(1), Int(1), Int(2), Int(3), Int(5)}},
+	} {
+		if c.result.EqualSet().Equal(c.expected) == false {
+			t.Fatalf("%v: %v, expected %v", c.name, c.result.EqualSet(), c.expected)
+		}
+	}
+	if (a.Count(Int(1)) != 3) || (a.Count(Int(3)) != 0) || (a.Distinct() != 3) || (a.Total() != 5) {
+		t.Fatal("operation modified the receiver")
+	}
+	c := a.Copy()
+	c.Add(Int(4), 1000000)
+	if (c.Total() != 1000005) || (c.Distinct() != 4) || a.Has(Int(4)) || a.Equal(c) {
+		t.Fatal("copy isn't independent")
+	}
+	if c.Sum(c).Count(Int(4)) != 2000000 {
+		t.Fatal("sum of large counts")
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+)
+
+const (
+	binaryMagic   = "uset"
+	binaryVersion = 1
+)
+
+const (
+	gobLayout     = 0
+	compactLayout = 1
+)
+
+// Errors returned when decoding the binary encoding of a set.
+var (
+	ErrChecksum = errors.New("unordered: binary set checksum mismatch")
+	ErrFormat   = errors.New("unordered: invalid binary set")
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+func compact(t reflect.Type) bool {
+	if t.Implements(binaryMarshalerType) == false {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		return t.Implements(binaryUnmarshalerType)
+	}
+	return reflect.PtrTo(t).Implements(binaryUnmarshalerType)
+}
+
+// Encodes the set in a versioned binary layout, also used by GobEncode. The item type must be registered with RegisterType. The layout is:
+//     magic       4 bytes "uset"
+//     version     1 byte, currently 1
+//     layout      1 byte, 0 for gob items or 1 for compact items
+//     name        uvarint length then the registered type name
+//     count       uvarint count of items
+//     items       count items in the layout
+//     checksum    4 bytes big-endian CRC-32 (Castagnoli) of all previous bytes
+// Items are in the compact layout when the registered type implements encoding.BinaryMarshaler and a pointer to it implements encoding.BinaryUnmarshaler; each item is then a uvarint length followed by its MarshalBinary bytes. A small fixed-size type like a coordinate can encode itself as two varints in a few bytes. Other types are encoded as one encoding/gob stream of count values.
+//
+// Duplicates are kept so the item counts compared by EqualSet.Equal are preserved. An empty set has no type name.
+func (a Set) MarshalBinary() ([]byte, error) {
+	name, err := a.registeredName()
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	b.WriteString(binaryMagic)
+	b.WriteByte(binaryVersion)
+	layout := byte(gobLayout)
+	if (len(a) > 0) && compact(a.typeof()) {
+		layout = compactLayout
+	}
+	b.WriteByte(layout)
+	var scratch [binary.MaxVarintLen64]byte
+	b.Write(scratch[:binary.PutUvarint(scratch[:], uint64(len(name)))])
+	b.WriteString(name)
+	b.Write(scratch[:binary.PutUvarint(scratch[:], uint64(len(a)))])
+	if layout == compactLayout {
+		for i, item := range a {
+			data, err := item.(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				return nil, fmt.Errorf("unordered: item %v: %w", i, err)
+			}
+			b.Write(scratch[:binary.PutUvarint(scratch[:], uint64(len(data)))])
+			b.Write(data)
+		}
+	} else if len(a) > 0 {
+		enc := gob.NewEncoder(&b)
+		for i, item := range a {
+			err = enc.Encode(item)
+			if err != nil {
+				return nil, fmt.Errorf("unordered: item %v: %w", i, err)
+			}
+		}
+	}
+	return sealed(b.Bytes()), nil
+}
+
+// Decodes a set encoded by MarshalBinary, replacing the receiver's items. ErrChecksum is returned if the data was corrupted.
+func (a *Set) UnmarshalBinary(data []byte) error {
+	body, err := unsealed(data, binaryMagic, binaryVersion)
+	if err != nil {
+		return err
+	}
+	if len(body) < 1 {
+		return ErrFormat
+	}
+	layout := body[0]
+	r := bytes.NewReader(body[1:])
+	l, err := binary.ReadUvarint(r)
+	if (err != nil) || (l > uint64(r.Len())) {
+		return ErrFormat
+	}
+	name := make([]byte, l)
+	r.Read(name)
+	count, err := binary.ReadUvarint(r)
+	if (err != nil) || (count > uint64(r.Len())) {
+		return ErrFormat
+	}
+	out := make(Set, count)
+	if count == 0 {
+		*a = out
+		return nil
+	}
+	t, err := registeredType(string(name))
+	if err != nil {
+		return err
+	}
+	switch layout {
+	case compactLayout:
+		if compact(t) == false {
+			return fmt.Errorf("unordered: registered type %v has no compact binary encoding", t)
+		}
+		for i := range out {
+			l, err := binary.ReadUvarint(r)
+			if (err != nil) || (l > uint64(r.Len())) {
+				return ErrFormat
+			}
+			data := make([]byte, l)
+			r.Read(data)
+			ptr, item := newItem(t)
+			err = ptr.(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+			if err != nil {
+				return fmt.Errorf("unordered: item %v is not a %q (%v): %w", i, name, t, err)
+			}
+			out[i] = item()
+		}
+	case gobLayout:
+		dec := gob.NewDecoder(r)
+		for i := range out {
+			ptr, item := newItem(t)
+			err = dec.Decode(ptr)
+			if err != nil {
+				return fmt.Errorf("unordered: item %v is not a %q (%v): %w", i, name, t, err)
+			}
+			out[i] = item()
+		}
+	default:
+		return fmt.Errorf("unordered: binary set layout %v not supported", layout)
+	}
+	if r.Len() != 0 {
+		return ErrFormat
+	}
+	*a = out
+	return nil
+}
+
+// Encodes the set with MarshalBinary.
+func (a Set) GobEncode() ([]byte, error) {
+	return a.MarshalBinary()
+}
+
+// Decodes the set with UnmarshalBinary.
+func (a *Set) GobDecode(data []byte) error {
+	return a.UnmarshalBinary(data)
+}
+
+// Encodes the set in the versioned binary layout described for Set.MarshalBinary.
+func (an EqualSet) MarshalBinary() ([]byte, error) {
+	return Set(an.items()).MarshalBinary()
+}
+
+// Decodes a set encoded by MarshalBinary, replacing the receiver's items. The registered type must be Comparable.
+func (an *EqualSet) UnmarshalBinary(data []byte) error {
+	var s Set
+	err := s.UnmarshalBinary(data)
+	if err != nil {
+		return err
+	}
+	if len(s) > 0 {
+		err = comparableType(s.typeof())
+		if err != nil {
+			return err
+		}
+	}
+	*an = s.equalset()
+	return nil
+}
+
+// Encodes the set with MarshalBinary.
+func (an EqualSet) GobEncode() ([]byte, error) {
+	return an.MarshalBinary()
+}
+
+// Decodes the set with UnmarshalBinary.
+func (an *EqualSet) GobDecode(data []byte) error {
+	return an.UnmarshalBinary(data)
+}
+
+// Like set but a nil receiver is an empty set.
+func (an EqualSet) items() Set {
+	if an == nil {
+		return Set{}
+	}
+	return an.set()
+}
+
+// Appends the 4 byte big-endian CRC-32 of the data that ends each binary layout in this package.
+func sealed(data []byte) []byte {
+	return binary.BigEndian.AppendUint32(data, crc32.Checksum(data, crcTable))
+}
+
+// Checks the magic, version, and checksum of a binary layout and returns the bytes between the version byte and the checksum.
+func unsealed(data []byte, magic string, version byte) ([]byte, error) {
+	if (len(data) < len(magic)+1+4) || (string(data[:len(magic)]) != magic) {
+		return nil, ErrFormat
+	}
+	body := data[:len(data)-4]
+	if crc32.Checksum(body, crcTable) != binary.BigEndian.Uint32(data[len(data)-4:]) {
+		return nil, ErrChecksum
+	}
+	if body[len(magic)] != version {
+		return nil, fmt.Errorf("unordered: %q version %v not supported", magic, body[len(magic)])
+	}
+	return body[len(magic)+1:], nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"testing"
+)
+
+// satisfies encoding.BinaryMarshaler for the compact binary set layout
+func (the Coordinate) MarshalBinary() ([]byte, error) {
+	out := make([]byte, 0, 2*binary.MaxVarintLen64)
+	out = binary.AppendVarint(out, int64(the.X))
+	out = binary.AppendVarint(out, int64(the.Y))
+	return out, nil
+}
+
+func (the *Coordinate) UnmarshalBinary(data []byte) error {
+	x, n := binary.Varint(data)
+	if n <= 0 {
+		return errors.New("bad X")
+	}
+	y, m := binary.Varint(data[n:])
+	if (m <= 0) || (n+m != len(data)) {
+		return errors.New("bad Y")
+	}
+	the.X = int(x)
+	the.Y = int(y)
+	return nil
+}
+
+func TestEqualSetBinary(t *testing.T) {
+	for i, c := range JSONCases {
+		data, err := c.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		var out EqualSet
+		err = out.UnmarshalBinary(data)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		if out.Equal(c) == false {
+			t.Fatalf("%v: %v not equal to %v", i, out, c)
+		}
+	}
+}
+
+func TestBinaryCompact(t *testing.T) {
+	data, err := EqualSet{Coordinate{1, -2}, Coordinate{1, -2}}.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{'u', 's', 'e', 't', 1, 1, 5, 'c', 'o', 'o', 'r', 'd', 2, 2, 2, 3, 2, 2, 3}
+	if bytes.Equal(data[:len(data)-4], expected) == false {
+		t.Fatalf("unexpected encoding %v", data)
+	}
+}
+
+func TestBinaryChecksum(t *testing.T) {
+	data, err := EqualSet{Int(1), Int(2), Int(3)}.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range data {
+		corrupt := append([]byte(nil), data...)
+		corrupt[i] ^= 0x10
+		var out EqualSet
+		err = out.UnmarshalBinary(corrupt)
+		if (err != ErrChecksum) && (err != ErrFormat) {
+			t.Fatalf("%v: unexpected error %v", i, err)
+		}
+	}
+}
+
+type GobCase struct {
+	Name  string
+	Items EqualSet
+	All   Set
+}
+
+func TestGob(t *testing.T) {
+	in := GobCase{
+		Name:  "gob",
+		Items: EqualSet{Coordinate{0, 0}, Coordinate{5, 6}, Coordinate{0, 0}},
+		All:   Set{String("a"), String("b")},
+	}
+	var b bytes.Buffer
+	err := gob.NewEncoder(&b).Encode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out GobCase
+	err = gob.NewDecoder(&b).Decode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (out.Name != in.Name) || (out.Items.Equal(in.Items) == false) || (out.All.equalset().Equal(in.All.equalset()) == false) {
+		t.Fatalf("%v not equal to %v", out, in)
+	}
+}
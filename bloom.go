@@ -0,0 +1,188 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// A BloomFilter answers Has for a set of items in a fraction of the memory of an EqualSet. Has is true for every added item, and also for a non-added item with about the false-positive rate the filter was sized for. Items can't be removed or listed.
+//
+// Bit positions come from the item's digest. The zero value can't be used; a filter is created by NewBloomFilter, NewBloomFilterFrom, or UnmarshalBinary.
+type BloomFilter struct {
+	bits   []uint64
+	hashes uint32
+	added  uint64
+}
+
+// Returned when filters or sketches of different sizes are merged.
+var ErrIncompatible = errors.New("unordered: merged filters have different parameters")
+
+const (
+	bloomMagic   = "ublm"
+	bloomVersion = 1
+)
+
+// Creates an empty BloomFilter sized to have the false-positive rate after the expected count of distinct items is added.
+func NewBloomFilter(expected int, falsePositive float64) *BloomFilter {
+	if asserting {
+		if expected < 0 {
+			panic("unordered: negative expected count")
+		}
+		if (falsePositive <= 0) || (falsePositive >= 1) {
+			panic("unordered: false-positive rate not between 0 and 1")
+		}
+	}
+	if expected == 0 {
+		expected = 1
+	}
+	n := float64(expected)
+	m := math.Ceil(-n * math.Log(falsePositive) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / n * math.Ln2)
+	if k < 1 {
+		k = 1
+	}
+	return &BloomFilter{
+		bits:   make([]uint64, (uint64(m)+63)/64),
+		hashes: uint32(k),
+	}
+}
+
+// Creates a BloomFilter sized for and holding the distinct items of the set.
+func NewBloomFilterFrom(set EqualSet, falsePositive float64) (*BloomFilter, error) {
+	out := NewBloomFilter(len(set), falsePositive)
+	for _, item := range set {
+		err := out.Add(item)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Adds the item to the filter. An error is returned if the item is neither Hashable nor an encoding.BinaryMarshaler.
+func (a *BloomFilter) Add(the Comparable) error {
+	if asserting {
+		if len(a.bits) == 0 {
+			panic("unordered: BloomFilter not created with NewBloomFilter")
+		}
+	}
+	d, err := digest(the)
+	if err != nil {
+		return err
+	}
+	m := uint64(len(a.bits)) * 64
+	h1, h2 := d, mix(d)|1
+	for i := uint64(0); i < uint64(a.hashes); i++ {
+		bit := (h1 + i*h2) % m
+		a.bits[bit/64] |= 1 << (bit % 64)
+	}
+	a.added++
+	return nil
+}
+
+// If the item may have been added then true is returned. If false then the item was not added.
+func (a *BloomFilter) Has(the Comparable) bool {
+	if asserting {
+		if len(a.bits) == 0 {
+			panic("unordered: BloomFilter not created with NewBloomFilter")
+		}
+	}
+	d, err := digest(the)
+	if err != nil {
+		return false
+	}
+	m := uint64(len(a.bits)) * 64
+	h1, h2 := d, mix(d)|1
+	for i := uint64(0); i < uint64(a.hashes); i++ {
+		bit := (h1 + i*h2) % m
+		if a.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Adds the items of the other filter to this one. The filters must have been created with the same expected count and false-positive rate, otherwise ErrIncompatible is returned.
+func (a *BloomFilter) Merge(with *BloomFilter) error {
+	if (len(a.bits) != len(with.bits)) || (a.hashes != with.hashes) {
+		return ErrIncompatible
+	}
+	for i, w := range with.bits {
+		a.bits[i] |= w
+	}
+	a.added += with.added
+	return nil
+}
+
+// Returns the probability that Has is true for an item that wasn't added, estimated from the fraction of set bits.
+func (a *BloomFilter) FalsePositiveRate() float64 {
+	set := 0
+	for _, w := range a.bits {
+		set += bits.OnesCount64(w)
+	}
+	return math.Pow(float64(set)/float64(len(a.bits)*64), float64(a.hashes))
+}
+
+// Returns the count of Add calls, including those of merged filters. Items added more than once are counted each time.
+func (a *BloomFilter) Added() uint64 {
+	return a.added
+}
+
+// Encodes the filter in a versioned binary layout:
+//     magic       4 bytes "ublm"
+//     version     1 byte, currently 1
+//     hashes      uvarint count of bit positions per item
+//     added       uvarint count of Add calls
+//     words       uvarint count of 64-bit words, then each big-endian
+//     checksum    4 bytes big-endian CRC-32 (Castagnoli) of all previous bytes
+func (a *BloomFilter) MarshalBinary() ([]byte, error) {
+	out := make([]byte, 0, len(bloomMagic)+1+3*binary.MaxVarintLen64+8*len(a.bits)+4)
+	out = append(out, bloomMagic...)
+	out = append(out, bloomVersion)
+	out = binary.AppendUvarint(out, uint64(a.hashes))
+	out = binary.AppendUvarint(out, a.added)
+	out = binary.AppendUvarint(out, uint64(len(a.bits)))
+	for _, w := range a.bits {
+		out = binary.BigEndian.AppendUint64(out, w)
+	}
+	return sealed(out), nil
+}
+
+// Decodes a filter encoded by MarshalBinary. ErrChecksum is returned if the data was corrupted.
+func (a *BloomFilter) UnmarshalBinary(data []byte) error {
+	body, err := unsealed(data, bloomMagic, bloomVersion)
+	if err != nil {
+		return err
+	}
+	hashes, n := binary.Uvarint(body)
+	if (n <= 0) || (hashes == 0) || (hashes > math.MaxUint32) {
+		return ErrFormat
+	}
+	body = body[n:]
+	added, n := binary.Uvarint(body)
+	if n <= 0 {
+		return ErrFormat
+	}
+	body = body[n:]
+	words, n := binary.Uvarint(body)
+	if (n <= 0) || (words == 0) || ((len(body)-n)%8 != 0) || (words != uint64(len(body)-n)/8) {
+		return ErrFormat
+	}
+	body = body[n:]
+	out := make([]uint64, words)
+	for i := range out {
+		out[i] = binary.BigEndian.Uint64(body[8*i:])
+	}
+	*a = BloomFilter{bits: out, hashes: uint32(hashes), added: added}
+	return nil
+}
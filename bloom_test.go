@@ -0,0 +1,125 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestBloomFilterFalsePositiveRate(t *testing.T) {
+	const n = 10000
+	for _, p := range []float64{0.1, 0.01, 0.001} {
+		for _, item := range []func(int) Comparable{
+			func(i int) Comparable { return Int(i) },
+			func(i int) Comparable { return Coordinate{i, -i} },
+		} {
+			f := NewBloomFilter(n, p)
+			for i := 0; i < n; i++ {
+				err := f.Add(item(i))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := 0; i < n; i++ {
+				if f.Has(item(i)) == false {
+					t.Fatalf("%v: false negative for %v", p, item(i))
+				}
+			}
+			positives := 0
+			const queries = 200000
+			for i := n; i < n+queries; i++ {
+				if f.Has(item(i)) {
+					positives++
+				}
+			}
+			rate := float64(positives) / queries
+			t.Logf("%T target %v: empirical %.5f, estimated %.5f, %v bytes", item(0), p, rate, f.FalsePositiveRate(), 8*len(f.bits))
+			if rate > 1.25*p {
+				t.Fatalf("%T target %v: empirical false-positive rate %v", item(0), p, rate)
+			}
+		}
+	}
+}
+
+func TestBloomFilterMerge(t *testing.T) {
+	a, err := NewBloomFilterFrom(EqualSet{Int(1), Int(2)}, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewBloomFilterFrom(EqualSet{Int(3), Int(4)}, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.Merge(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 4; i++ {
+		if a.Has(Int(i)) == false {
+			t.Fatal("merged filter lacks", i)
+		}
+	}
+	if a.Added() != 4 {
+		t.Fatal(a.Added())
+	}
+	if a.Merge(NewBloomFilter(1000, 0.01)) != ErrIncompatible {
+		t.Fatal("merged filters of different sizes")
+	}
+	_, err = NewBloomFilterFrom(EqualSet{String("a")}, 0.01)
+	if err == nil {
+		t.Fatal("added an item without a hash or binary encoding")
+	}
+}
+
+func TestBloomFilterBinary(t *testing.T) {
+	f, err := NewBloomFilterFrom(EqualSet{Int(1), Int(2), Int(3)}, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g BloomFilter
+	err = g.UnmarshalBinary(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (g.Has(Int(1)) == false) || (g.Added() != 3) || (g.Merge(f) != nil) {
+		t.Fatal("decoded filter differs")
+	}
+	data[len(data)-5] ^= 1
+	if g.UnmarshalBinary(data) != ErrChecksum {
+		t.Fatal("corrupt filter decoded")
+	}
+}
+
+func TestBloomFilterBinaryWordCount(t *testing.T) {
+	// 8 bytes for each of 2^61+1 words overflows to 8, matching the one word of data
+	data := append([]byte(bloomMagic), bloomVersion)
+	data = binary.AppendUvarint(data, 3)
+	data = binary.AppendUvarint(data, 0)
+	data = binary.AppendUvarint(data, 1<<61+1)
+	data = sealed(append(data, make([]byte, 8)...))
+	var f BloomFilter
+	if f.UnmarshalBinary(data) != ErrFormat {
+		t.Fatal("filter with a bad word count decoded")
+	}
+}
+
+func TestBloomFilterZeroValue(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("zero value filter used")
+		}
+	}()
+	var zero BloomFilter
+	zero.Has(Int(1))
+}
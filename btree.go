@@ -0,0 +1,854 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// A BTreeSet is a set stored in a single file as a B+ tree of pages, for sets too large to keep in memory as an EqualSet. Items must be Ordered, registered with RegisterType, and have the compact binary encoding described for Set.MarshalBinary: the type implements encoding.BinaryMarshaler and a pointer to it encoding.BinaryUnmarshaler. An encoded item must be at most MaxBTreeKey bytes.
+//
+// Like an EqualSet the tree is a multiset: each distinct item is stored once with a count of its copies.
+//
+// Writes are crash safe by copy-on-write. A change writes new pages for the path from the changed leaf to the root and never modifies a page reachable from the committed root. The file is synced, then the new root is committed by writing one of two alternating header pages, which carry a sequence number and a checksum, and syncing again. After a crash the valid header with the higher sequence number is used, so the tree is as of the last completed change. Pages that aren't reachable from the root are found by walking the tree when the set is opened, and reused.
+//
+// Removing items doesn't rebalance the tree. Empty leaves are deleted, but sparsely filled pages are kept.
+//
+// The methods are safe to call from multiple goroutines.
+type BTreeSet struct {
+	mutex    sync.Mutex
+	file     *os.File
+	t        reflect.Type
+	name     string
+	header   btreeHeader
+	slot     int // header page the committed header is in
+	free     []uint64
+	pending  []uint64 // pages freed by the change being made, reusable after it's committed
+	fresh    []uint64 // pages allocated by the change being made
+	modified bool
+}
+
+// The page size of a BTreeSet file.
+const BTreePageSize = 4096
+
+// The largest encoded item a BTreeSet stores.
+const MaxBTreeKey = 1024
+
+const (
+	btreeMagic   = "ubtr"
+	btreeVersion = 1
+	leafPage     = 1
+	branchPage   = 2
+)
+
+type btreeHeader struct {
+	sequence uint64
+	root     uint64
+	pages    uint64 // count of pages in the file, including the two header pages
+	total    uint64 // count of items including duplicates
+	distinct uint64
+}
+
+type btreeNode struct {
+	leaf     bool
+	items    []Ordered // leaf items or branch separators
+	keys     [][]byte  // the encoding of each item
+	counts   []uint64  // leaf only
+	children []uint64  // branch only, one more than items
+}
+
+// Opens the BTreeSet file at the path, creating it if it doesn't exist. The sample is an item of the set's type, which must match the type of an existing file.
+func OpenBTreeSet(path string, sample Ordered) (*BTreeSet, error) {
+	if asserting {
+		if sample == nil {
+			panic("unordered: nil sample")
+		}
+	}
+	t := reflect.TypeOf(sample)
+	name, err := registeredName(t)
+	if err != nil {
+		return nil, err
+	}
+	if compact(t) == false {
+		return nil, fmt.Errorf("unordered: registered type %v has no compact binary encoding", t)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+	a := &BTreeSet{
+		file: f,
+		t:    t,
+		name: name,
+	}
+	info, err := f.Stat()
+	if err == nil {
+		if info.Size() == 0 {
+			err = a.create()
+		} else {
+			err = a.load()
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// Closes the file.
+func (a *BTreeSet) Close() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.file.Close()
+}
+
+// Returns the count of items including duplicates.
+func (a *BTreeSet) Len() uint64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.header.total
+}
+
+// Returns the count of distinct items.
+func (a *BTreeSet) Distinct() uint64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.header.distinct
+}
+
+// Adds an item to the set. Duplicates are allowed.
+func (a *BTreeSet) Add(the Ordered) error {
+	return a.AddAll(EqualSet{the})
+}
+
+// Adds every item of the set in one committed change.
+func (a *BTreeSet) AddAll(the EqualSet) error {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil set")
+		}
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for _, item := range the {
+		key, err := a.encode(item)
+		if err != nil {
+			a.abort()
+			return err
+		}
+		err = a.insert(item.(Ordered), key, 1)
+		if err != nil {
+			a.abort()
+			return err
+		}
+	}
+	return a.commit()
+}
+
+// Removes one matching item. Removing an item the set doesn't have isn't an error.
+func (a *BTreeSet) Remove(the Ordered) error {
+	return a.remove(the, false)
+}
+
+// Removes all matching items.
+func (a *BTreeSet) RemoveAll(the Ordered) error {
+	return a.remove(the, true)
+}
+
+// If the set has the item then true is returned.
+func (a *BTreeSet) Has(the Ordered) (bool, error) {
+	count, err := a.Count(the)
+	return count > 0, err
+}
+
+// Returns the count of copies of the item in the set.
+func (a *BTreeSet) Count(the Ordered) (uint64, error) {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	page := a.header.root
+	for {
+		n, err := a.read(page)
+		if err != nil {
+			return 0, err
+		}
+		if n.leaf {
+			i, found := n.find(the)
+			if found == false {
+				return 0, nil
+			}
+			return n.counts[i], nil
+		}
+		page = n.children[n.child(the)]
+	}
+}
+
+// Calls the function with each distinct item and its count in ascending order, starting with the first item not less than lo and stopping before the first item not less than hi. A nil lo or hi is unbounded. The scan stops if the function returns false. The set must not be modified by the function.
+func (a *BTreeSet) Range(lo, hi Ordered, fn func(item Ordered, count uint64) bool) error {
+	if asserting {
+		if fn == nil {
+			panic("unordered: nil function")
+		}
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	_, err := a.scan(a.header.root, lo, hi, fn)
+	return err
+}
+
+// Returns the items of the set as an EqualSet, with duplicates. The set must fit in memory.
+func (a *BTreeSet) Set() (EqualSet, error) {
+	out := make(EqualSet, 0)
+	err := a.Range(nil, nil, func(item Ordered, count uint64) bool {
+		for i := uint64(0); i < count; i++ {
+			out = append(out, item)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// If the tree and the set contain an equal count of each item then true is returned, like EqualSet.Equal. The items of the set are sorted and compared in one ordered scan of the tree.
+func (a *BTreeSet) Equal(to EqualSet) (bool, error) {
+	if asserting {
+		if to == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	if uint64(len(to)) != a.Len() {
+		return false, nil
+	}
+	other := sortedCounts(to)
+	i := 0
+	equal := true
+	err := a.Range(nil, nil, func(item Ordered, count uint64) bool {
+		if (i >= len(other)) || (other[i].item.Equal(item) == false) || (other[i].count != count) {
+			equal = false
+			return false
+		}
+		i++
+		return true
+	})
+	if err != nil {
+		return false, err
+	}
+	return equal && (i == len(other)), nil
+}
+
+// Provides a set of the items not in both the tree and the argument set, like EqualSet.Diff. Duplicates are not removed. The items of the set are sorted and compared in one ordered scan of the tree.
+func (a *BTreeSet) Diff(from EqualSet) (EqualSet, error) {
+	if asserting {
+		if from == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	other := sortedCounts(from)
+	out := make(EqualSet, 0)
+	add := func(item Ordered, count uint64) {
+		for j := uint64(0); j < count; j++ {
+			out = append(out, item)
+		}
+	}
+	i := 0
+	err := a.Range(nil, nil, func(item Ordered, count uint64) bool {
+		for (i < len(other)) && other[i].item.Less(item) {
+			add(other[i].item, other[i].count)
+			i++
+		}
+		if (i < len(other)) && other[i].item.Equal(item) {
+			i++
+			return true
+		}
+		add(item, count)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	for ; i < len(other); i++ {
+		add(other[i].item, other[i].count)
+	}
+	return out, nil
+}
+
+func (a *BTreeSet) remove(the Ordered, all bool) error {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	root, found, empty, err := a.delete(a.header.root, the, all)
+	if err != nil {
+		a.abort()
+		return err
+	}
+	if found == false {
+		return nil
+	}
+	if empty {
+		root, err = a.write(&btreeNode{leaf: true})
+		if err != nil {
+			a.abort()
+			return err
+		}
+	}
+	a.header.root = root
+	// a root branch with one child is replaced by the child
+	for {
+		n, err := a.read(a.header.root)
+		if err != nil {
+			a.abort()
+			return err
+		}
+		if n.leaf || (len(n.children) > 1) {
+			break
+		}
+		a.release(a.header.root)
+		a.header.root = n.children[0]
+	}
+	return a.commit()
+}
+
+func (a *BTreeSet) encode(the Comparable) ([]byte, error) {
+	if reflect.TypeOf(the) != a.t {
+		return nil, fmt.Errorf("unordered: item type %v doesn't match set type %v", reflect.TypeOf(the), a.t)
+	}
+	key, err := the.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if len(key) > MaxBTreeKey {
+		return nil, fmt.Errorf("unordered: encoded item is %v bytes, more than the limit of %v", len(key), MaxBTreeKey)
+	}
+	return key, nil
+}
+
+func (a *BTreeSet) decode(key []byte) (Ordered, error) {
+	ptr, item := newItem(a.t)
+	err := ptr.(encoding.BinaryUnmarshaler).UnmarshalBinary(key)
+	if err != nil {
+		return nil, err
+	}
+	return item().(Ordered), nil
+}
+
+// Inserts count copies of the item below the root, replacing the root.
+func (a *BTreeSet) insert(the Ordered, key []byte, count uint64) error {
+	left, sep, sepKey, right, added, err := a.insertAt(a.header.root, the, key, count)
+	if err != nil {
+		return err
+	}
+	if right != 0 {
+		left, err = a.write(&btreeNode{
+			items:    []Ordered{sep},
+			keys:     [][]byte{sepKey},
+			children: []uint64{left, right},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	a.header.root = left
+	a.header.total += count
+	if added {
+		a.header.distinct++
+	}
+	return nil
+}
+
+// Returns the page replacing the page, and if the page was split the separator and the right page.
+func (a *BTreeSet) insertAt(page uint64, the Ordered, key []byte, count uint64) (uint64, Ordered, []byte, uint64, bool, error) {
+	n, err := a.read(page)
+	if err != nil {
+		return 0, nil, nil, 0, false, err
+	}
+	added := false
+	if n.leaf {
+		i, found := n.find(the)
+		if found {
+			n.counts[i] += count
+		} else {
+			n.items = append(n.items[:i], append([]Ordered{the}, n.items[i:]...)...)
+			n.keys = append(n.keys[:i], append([][]byte{key}, n.keys[i:]...)...)
+			n.counts = append(n.counts[:i], append([]uint64{count}, n.counts[i:]...)...)
+			added = true
+		}
+	} else {
+		i := n.child(the)
+		left, sep, sepKey, right, childAdded, err := a.insertAt(n.children[i], the, key, count)
+		if err != nil {
+			return 0, nil, nil, 0, false, err
+		}
+		added = childAdded
+		n.children[i] = left
+		if right != 0 {
+			n.items = append(n.items[:i], append([]Ordered{sep}, n.items[i:]...)...)
+			n.keys = append(n.keys[:i], append([][]byte{sepKey}, n.keys[i:]...)...)
+			n.children = append(n.children[:i+1], append([]uint64{right}, n.children[i+1:]...)...)
+		}
+	}
+	a.release(page)
+	if n.size() <= BTreePageSize {
+		p, err := a.write(n)
+		return p, nil, nil, 0, added, err
+	}
+	l, sep, sepKey, r := n.split()
+	left, err := a.write(l)
+	if err != nil {
+		return 0, nil, nil, 0, false, err
+	}
+	right, err := a.write(r)
+	return left, sep, sepKey, right, added, err
+}
+
+// Returns the page replacing the page, if the item was found, and if the page became empty and was deleted.
+func (a *BTreeSet) delete(page uint64, the Ordered, all bool) (uint64, bool, bool, error) {
+	n, err := a.read(page)
+	if err != nil {
+		return 0, false, false, err
+	}
+	if n.leaf {
+		i, found := n.find(the)
+		if found == false {
+			return page, false, false, nil
+		}
+		if all || (n.counts[i] == 1) {
+			a.header.total -= n.counts[i]
+			a.header.distinct--
+			n.items = append(n.items[:i], n.items[i+1:]...)
+			n.keys = append(n.keys[:i], n.keys[i+1:]...)
+			n.counts = append(n.counts[:i], n.counts[i+1:]...)
+		} else {
+			a.header.total--
+			n.counts[i]--
+		}
+	} else {
+		i := n.child(the)
+		child, found, empty, err := a.delete(n.children[i], the, all)
+		if (err != nil) || (found == false) {
+			return page, found, false, err
+		}
+		if empty {
+			n.children = append(n.children[:i], n.children[i+1:]...)
+			s := i - 1
+			if i == 0 {
+				s = 0
+			}
+			n.items = append(n.items[:s], n.items[s+1:]...)
+			n.keys = append(n.keys[:s], n.keys[s+1:]...)
+		} else {
+			n.children[i] = child
+		}
+	}
+	a.release(page)
+	if (n.leaf && (len(n.items) == 0)) || ((n.leaf == false) && (len(n.children) == 0)) {
+		return 0, true, true, nil
+	}
+	p, err := a.write(n)
+	return p, true, false, err
+}
+
+func (a *BTreeSet) scan(page uint64, lo, hi Ordered, fn func(Ordered, uint64) bool) (bool, error) {
+	n, err := a.read(page)
+	if err != nil {
+		return false, err
+	}
+	if n.leaf {
+		for i, item := range n.items {
+			if (lo != nil) && item.Less(lo) {
+				continue
+			}
+			if (hi != nil) && (item.Less(hi) == false) {
+				return false, nil
+			}
+			if fn(item, n.counts[i]) == false {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	for i, child := range n.children {
+		// child i holds items not less than separator i-1 and less than separator i
+		if (lo != nil) && (i < len(n.items)) && (lo.Less(n.items[i]) == false) {
+			continue
+		}
+		if (hi != nil) && (i > 0) && (n.items[i-1].Less(hi) == false) {
+			return false, nil
+		}
+		more, err := a.scan(child, lo, hi, fn)
+		if (err != nil) || (more == false) {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Returns the index of the first item not less than the argument, and if that item is equal to it.
+func (an *btreeNode) find(the Ordered) (int, bool) {
+	i := sort.Search(len(an.items), func(i int) bool {
+		return an.items[i].Less(the) == false
+	})
+	return i, (i < len(an.items)) && an.items[i].Equal(the)
+}
+
+// Returns the index of the child of a branch that can hold the item.
+func (an *btreeNode) child(the Ordered) int {
+	return sort.Search(len(an.items), func(i int) bool {
+		return the.Less(an.items[i])
+	})
+}
+
+func (an *btreeNode) size() int {
+	size := 1 + 2 + 4
+	if an.leaf == false {
+		size += 8
+	}
+	for i, key := range an.keys {
+		size += uvarintLen(uint64(len(key))) + len(key)
+		if an.leaf {
+			size += uvarintLen(an.counts[i])
+		} else {
+			size += 8
+		}
+	}
+	return size
+}
+
+// Splits an overfull node near the middle of its encoded size.
+func (an *btreeNode) split() (*btreeNode, Ordered, []byte, *btreeNode) {
+	half := an.size() / 2
+	size := 0
+	m := 0
+	for m < len(an.keys)-1 {
+		size += len(an.keys[m]) + 9
+		if size >= half {
+			break
+		}
+		m++
+	}
+	if m == 0 {
+		m = 1
+	}
+	if an.leaf {
+		left := &btreeNode{leaf: true, items: an.items[:m:m], keys: an.keys[:m:m], counts: an.counts[:m:m]}
+		right := &btreeNode{leaf: true, items: an.items[m:], keys: an.keys[m:], counts: an.counts[m:]}
+		return left, right.items[0], right.keys[0], right
+	}
+	left := &btreeNode{items: an.items[:m:m], keys: an.keys[:m:m], children: an.children[: m+1 : m+1]}
+	right := &btreeNode{items: an.items[m+1:], keys: an.keys[m+1:], children: an.children[m+1:]}
+	return left, an.items[m], an.keys[m], right
+}
+
+func uvarintLen(x uint64) int {
+	var b [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(b[:], x)
+}
+
+// A node page is a kind byte, a 2 byte big-endian item count, the entries, and a CRC-32 (Castagnoli) of the page in the last 4 bytes. A leaf entry is a uvarint key length, the key, and a uvarint count. A branch starts with the 8 byte first child page, and each entry is a uvarint key length, the key, and the 8 byte page of the child after it.
+func (an *btreeNode) encode() []byte {
+	out := make([]byte, 0, BTreePageSize)
+	if an.leaf {
+		out = append(out, leafPage)
+	} else {
+		out = append(out, branchPage)
+	}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(an.keys)))
+	if an.leaf == false {
+		out = binary.BigEndian.AppendUint64(out, an.children[0])
+	}
+	for i, key := range an.keys {
+		out = binary.AppendUvarint(out, uint64(len(key)))
+		out = append(out, key...)
+		if an.leaf {
+			out = binary.AppendUvarint(out, an.counts[i])
+		} else {
+			out = binary.BigEndian.AppendUint64(out, an.children[i+1])
+		}
+	}
+	out = out[:BTreePageSize]
+	binary.BigEndian.PutUint32(out[BTreePageSize-4:], crc32.Checksum(out[:BTreePageSize-4], crcTable))
+	return out
+}
+
+var errBTreePage = errors.New("unordered: corrupt BTreeSet page")
+
+func (a *BTreeSet) read(page uint64) (*btreeNode, error) {
+	data := make([]byte, BTreePageSize)
+	_, err := a.file.ReadAt(data, int64(page)*BTreePageSize)
+	if err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(data[:BTreePageSize-4], crcTable) != binary.BigEndian.Uint32(data[BTreePageSize-4:]) {
+		return nil, fmt.Errorf("%w %v: checksum mismatch", errBTreePage, page)
+	}
+	n := &btreeNode{leaf: data[0] == leafPage}
+	if (data[0] != leafPage) && (data[0] != branchPage) {
+		return nil, fmt.Errorf("%w %v: unknown kind %v", errBTreePage, page, data[0])
+	}
+	count := int(binary.BigEndian.Uint16(data[1:]))
+	r := data[3 : BTreePageSize-4]
+	if n.leaf == false {
+		n.children = append(make([]uint64, 0, count+1), binary.BigEndian.Uint64(r))
+		r = r[8:]
+	} else {
+		n.counts = make([]uint64, 0, count)
+	}
+	n.items = make([]Ordered, 0, count)
+	n.keys = make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		l, k := binary.Uvarint(r)
+		if (k <= 0) || (uint64(len(r)-k) < l) {
+			return nil, fmt.Errorf("%w %v: bad key length", errBTreePage, page)
+		}
+		key := r[k : k+int(l)]
+		r = r[k+int(l):]
+		item, err := a.decode(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w %v: %v", errBTreePage, page, err)
+		}
+		n.items = append(n.items, item)
+		n.keys = append(n.keys, key)
+		if n.leaf {
+			c, k := binary.Uvarint(r)
+			if k <= 0 {
+				return nil, fmt.Errorf("%w %v: bad count", errBTreePage, page)
+			}
+			n.counts = append(n.counts, c)
+			r = r[k:]
+		} else {
+			if len(r) < 8 {
+				return nil, fmt.Errorf("%w %v: truncated", errBTreePage, page)
+			}
+			n.children = append(n.children, binary.BigEndian.Uint64(r))
+			r = r[8:]
+		}
+	}
+	return n, nil
+}
+
+// Writes the node to a page that isn't reachable from the committed root.
+func (a *BTreeSet) write(the *btreeNode) (uint64, error) {
+	if the.size() > BTreePageSize {
+		return 0, fmt.Errorf("unordered: BTreeSet node of %v bytes doesn't fit in a page", the.size())
+	}
+	var page uint64
+	if len(a.free) > 0 {
+		page = a.free[len(a.free)-1]
+		a.free = a.free[:len(a.free)-1]
+	} else {
+		page = a.header.pages
+		a.header.pages++
+	}
+	a.fresh = append(a.fresh, page)
+	a.modified = true
+	_, err := a.file.WriteAt(the.encode(), int64(page)*BTreePageSize)
+	return page, err
+}
+
+// Marks a page replaced by the change being made. A page written by the same change is reusable immediately.
+func (a *BTreeSet) release(page uint64) {
+	for i, p := range a.fresh {
+		if p == page {
+			a.fresh = append(a.fresh[:i], a.fresh[i+1:]...)
+			a.free = append(a.free, page)
+			return
+		}
+	}
+	a.pending = append(a.pending, page)
+}
+
+// Syncs the new pages and then commits the new root to the header slot not holding the current header.
+func (a *BTreeSet) commit() error {
+	if a.modified == false {
+		return nil
+	}
+	err := a.file.Sync()
+	if err != nil {
+		a.abort()
+		return err
+	}
+	a.header.sequence++
+	slot := 1 - a.slot
+	_, err = a.file.WriteAt(a.header.encode(a.name), int64(slot)*BTreePageSize)
+	if err == nil {
+		err = a.file.Sync()
+	}
+	if err != nil {
+		a.header.sequence--
+		a.abort()
+		return err
+	}
+	a.slot = slot
+	a.free = append(a.free, a.pending...)
+	a.pending = a.pending[:0]
+	a.fresh = a.fresh[:0]
+	a.modified = false
+	return nil
+}
+
+// Discards an uncommitted change by reloading the committed header.
+func (a *BTreeSet) abort() {
+	if a.modified == false {
+		return
+	}
+	committed, err := a.readHeader(a.slot)
+	if err == nil {
+		a.header = committed
+	}
+	// pages past the committed page count are appended again by write, so only earlier pages are reusable
+	free := a.free[:0]
+	for _, list := range [][]uint64{a.free, a.fresh} {
+		for _, page := range list {
+			if page < a.header.pages {
+				free = append(free, page)
+			}
+		}
+	}
+	a.free = free
+	a.pending = a.pending[:0]
+	a.fresh = a.fresh[:0]
+	a.modified = false
+}
+
+// The header page is the magic, a version byte, the sequence, root, page count, total, and distinct count as 8 byte big-endian integers, a uvarint length and the registered type name, and a CRC-32 (Castagnoli) of the previous bytes.
+func (an btreeHeader) encode(name string) []byte {
+	out := make([]byte, 0, BTreePageSize)
+	out = append(out, btreeMagic...)
+	out = append(out, btreeVersion)
+	for _, v := range []uint64{an.sequence, an.root, an.pages, an.total, an.distinct} {
+		out = binary.BigEndian.AppendUint64(out, v)
+	}
+	out = binary.AppendUvarint(out, uint64(len(name)))
+	out = append(out, name...)
+	out = binary.BigEndian.AppendUint32(out, crc32.Checksum(out, crcTable))
+	return out[:BTreePageSize]
+}
+
+func (a *BTreeSet) readHeader(slot int) (btreeHeader, error) {
+	data := make([]byte, BTreePageSize)
+	_, err := a.file.ReadAt(data, int64(slot)*BTreePageSize)
+	if (err != nil) && (err != io.EOF) {
+		return btreeHeader{}, err
+	}
+	if (string(data[:4]) != btreeMagic) || (data[4] != btreeVersion) {
+		return btreeHeader{}, ErrFormat
+	}
+	var h btreeHeader
+	r := data[5:]
+	for _, v := range []*uint64{&h.sequence, &h.root, &h.pages, &h.total, &h.distinct} {
+		*v = binary.BigEndian.Uint64(r)
+		r = r[8:]
+	}
+	l, k := binary.Uvarint(r)
+	if (k <= 0) || (l > MaxBTreeKey) {
+		return btreeHeader{}, ErrFormat
+	}
+	end := 5 + 5*8 + k + int(l)
+	if crc32.Checksum(data[:end], crcTable) != binary.BigEndian.Uint32(data[end:]) {
+		return btreeHeader{}, ErrChecksum
+	}
+	name := string(r[k : k+int(l)])
+	if name != a.name {
+		return btreeHeader{}, fmt.Errorf("unordered: BTreeSet file holds %q items, not %q", name, a.name)
+	}
+	return h, nil
+}
+
+func (a *BTreeSet) create() error {
+	a.header = btreeHeader{pages: 2}
+	root, err := a.write(&btreeNode{leaf: true})
+	if err != nil {
+		return err
+	}
+	a.header.root = root
+	a.slot = 1
+	err = a.commit()
+	if err != nil {
+		return err
+	}
+	// both header slots are valid so a torn write of either leaves the other
+	a.header.sequence++
+	_, err = a.file.WriteAt(a.header.encode(a.name), 1*BTreePageSize)
+	if err == nil {
+		err = a.file.Sync()
+	}
+	a.slot = 1
+	return err
+}
+
+func (a *BTreeSet) load() error {
+	var err error
+	headers := make([]btreeHeader, 2)
+	valid := 0
+	for slot := range headers {
+		headers[slot], err = a.readHeader(slot)
+		if err == nil {
+			valid++
+			if (valid == 1) || (headers[slot].sequence > a.header.sequence) {
+				a.header = headers[slot]
+				a.slot = slot
+			}
+			continue
+		}
+		if (err != ErrFormat) && (err != ErrChecksum) {
+			return err
+		}
+	}
+	if valid == 0 {
+		return fmt.Errorf("unordered: no valid BTreeSet header: %w", err)
+	}
+	reachable := make(map[uint64]bool)
+	err = a.walk(a.header.root, reachable)
+	if err != nil {
+		return err
+	}
+	for p := uint64(2); p < a.header.pages; p++ {
+		if reachable[p] == false {
+			a.free = append(a.free, p)
+		}
+	}
+	return nil
+}
+
+func (a *BTreeSet) walk(page uint64, reachable map[uint64]bool) error {
+	if (page < 2) || (page >= a.header.pages) || reachable[page] {
+		return fmt.Errorf("%w %v: bad page reference", errBTreePage, page)
+	}
+	reachable[page] = true
+	n, err := a.read(page)
+	if err != nil {
+		return err
+	}
+	for _, child := range n.children {
+		err = a.walk(child, reachable)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
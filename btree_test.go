@@ -0,0 +1,290 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openBTreeSet(t *testing.T, path string) *BTreeSet {
+	set, err := OpenBTreeSet(path, Coordinate{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+func checkBTreeSet(t *testing.T, set *BTreeSet, expected EqualSet) {
+	equal, err := set.Equal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if equal == false {
+		out, _ := set.Set()
+		t.Fatalf("%v items not equal to %v expected", len(out), len(expected))
+	}
+	if set.Len() != uint64(len(expected)) {
+		t.Fatalf("length %v, expected %v", set.Len(), len(expected))
+	}
+	if set.Distinct() != uint64(len(expected.Reduce())) {
+		t.Fatalf("distinct %v, expected %v", set.Distinct(), len(expected.Reduce()))
+	}
+}
+
+func TestBTreeSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "btree")
+	set := openBTreeSet(t, path)
+	r := rand.New(rand.NewSource(1))
+	expected := EqualSet{}
+	batch := EqualSet{}
+	for i := 0; i < 3000; i++ {
+		c := Coordinate{r.Intn(200), r.Intn(1000000)}
+		if i%10 == 0 {
+			c = Coordinate{0, r.Intn(5)}
+		}
+		batch = append(batch, c)
+		expected = append(expected, c)
+	}
+	if err := set.AddAll(batch); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1500; i++ {
+		c := expected[r.Intn(len(expected))].(Coordinate)
+		var err error
+		switch i % 3 {
+		case 0:
+			err = set.Remove(c)
+			expected, _ = removeOne(expected, c)
+		case 1:
+			err = set.RemoveAll(c)
+			expected, _ = removeAll(expected, c)
+		case 2:
+			err = set.Add(c)
+			expected = append(expected, c)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkBTreeSet(t, set, expected)
+	has, err := set.Has(Coordinate{-1, -1})
+	if (err != nil) || has {
+		t.Fatalf("has missing item, %v", err)
+	}
+	if err := set.Close(); err != nil {
+		t.Fatal(err)
+	}
+	set = openBTreeSet(t, path)
+	defer set.Close()
+	checkBTreeSet(t, set, expected)
+	for _, c := range expected.Reduce() {
+		if err := set.RemoveAll(c.(Coordinate)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkBTreeSet(t, set, EqualSet{})
+	if err := set.Add(Coordinate{1, 1}); err != nil {
+		t.Fatal(err)
+	}
+	checkBTreeSet(t, set, EqualSet{Coordinate{1, 1}})
+	if info, _ := os.Stat(path); info.Size() > 1000*BTreePageSize {
+		t.Fatalf("freed pages not reused, file is %v pages", info.Size()/BTreePageSize)
+	}
+}
+
+func TestBTreeSetRange(t *testing.T) {
+	set := openBTreeSet(t, filepath.Join(t.TempDir(), "btree"))
+	defer set.Close()
+	all := EqualSet{}
+	for x := 0; x < 50; x++ {
+		for y := 0; y < 50; y++ {
+			all = append(all, Coordinate{x, y})
+		}
+	}
+	all = append(all, Coordinate{10, 10})
+	if err := set.AddAll(all); err != nil {
+		t.Fatal(err)
+	}
+	out := EqualSet{}
+	var last Ordered
+	err := set.Range(Coordinate{10, 5}, Coordinate{12, 0}, func(item Ordered, count uint64) bool {
+		if (last != nil) && (item.Less(last) || item.Equal(last)) {
+			t.Fatal("range out of order")
+		}
+		last = item
+		for i := uint64(0); i < count; i++ {
+			out = append(out, item)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := EqualSet{Coordinate{10, 10}}
+	for y := 5; y < 50; y++ {
+		expected = append(expected, Coordinate{10, y})
+	}
+	for y := 0; y < 50; y++ {
+		expected = append(expected, Coordinate{11, y})
+	}
+	if out.Equal(expected) == false {
+		t.Fatalf("range %v items, expected %v", len(out), len(expected))
+	}
+	count := 0
+	set.Range(nil, nil, func(item Ordered, _ uint64) bool {
+		count++
+		return count < 10
+	})
+	if count != 10 {
+		t.Fatal("range didn't stop")
+	}
+}
+
+func TestBTreeSetDiff(t *testing.T) {
+	set := openBTreeSet(t, filepath.Join(t.TempDir(), "btree"))
+	defer set.Close()
+	set.AddAll(EqualSet{Coordinate{1, 1}, Coordinate{2, 2}, Coordinate{2, 2}, Coordinate{3, 3}})
+	other := EqualSet{Coordinate{2, 2}, Coordinate{4, 4}, Coordinate{0, 0}, Coordinate{0, 0}}
+	diff, err := set.Diff(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, _ := set.Set()
+	if diff.Equal(full.Diff(other)) == false {
+		t.Fatalf("diff %v not equal to %v", diff, full.Diff(other))
+	}
+	equal, err := set.Equal(EqualSet{Coordinate{1, 1}, Coordinate{2, 2}, Coordinate{3, 3}, Coordinate{3, 3}})
+	if (err != nil) || equal {
+		t.Fatal("sets with different counts equal")
+	}
+}
+
+func TestBTreeSetTornHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "btree")
+	set := openBTreeSet(t, path)
+	set.Add(Coordinate{1, 1})
+	set.Add(Coordinate{2, 2})
+	slot := set.slot
+	set.Close()
+	f, err := os.OpenFile(path, os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteAt([]byte{0xff, 0xff}, int64(slot)*BTreePageSize+20)
+	f.Close()
+	set = openBTreeSet(t, path)
+	defer set.Close()
+	checkBTreeSet(t, set, EqualSet{Coordinate{1, 1}})
+	if err := set.Add(Coordinate{3, 3}); err != nil {
+		t.Fatal(err)
+	}
+	checkBTreeSet(t, set, EqualSet{Coordinate{1, 1}, Coordinate{3, 3}})
+	if _, err := OpenBTreeSet(path, Int(0)); err == nil {
+		t.Fatal("opened with a different type")
+	}
+}
+
+// Padded encodes to nearly MaxBTreeKey bytes so a few items fill a page, making deep trees.
+type Padded int
+
+func init() {
+	RegisterType("padded", Padded(0))
+}
+
+func (a Padded) Equal(to Comparable) bool {
+	return a == to.(Padded)
+}
+
+func (a Padded) Less(than Comparable) bool {
+	return a < than.(Padded)
+}
+
+func (a Padded) MarshalBinary() ([]byte, error) {
+	out := make([]byte, MaxBTreeKey)
+	binary.BigEndian.PutUint64(out, uint64(a))
+	return out, nil
+}
+
+func (a *Padded) UnmarshalBinary(data []byte) error {
+	if len(data) != MaxBTreeKey {
+		return errors.New("bad length")
+	}
+	*a = Padded(binary.BigEndian.Uint64(data))
+	return nil
+}
+
+func TestBTreeSetDeep(t *testing.T) {
+	set, err := OpenBTreeSet(filepath.Join(t.TempDir(), "btree"), Padded(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer set.Close()
+	r := rand.New(rand.NewSource(2))
+	expected := EqualSet{}
+	for i := 0; i < 400; i++ {
+		p := Padded(r.Intn(300))
+		if (i%4 == 3) && (len(expected) > 0) {
+			p = expected[r.Intn(len(expected))].(Padded)
+			set.RemoveAll(p)
+			expected, _ = removeAll(expected, p)
+			continue
+		}
+		set.Add(p)
+		expected = append(expected, p)
+	}
+	checkBTreeSet(t, set, expected)
+	var last Ordered
+	err = set.Range(Padded(100), Padded(200), func(item Ordered, count uint64) bool {
+		if (item.Less(Padded(100))) || (item.Less(Padded(200)) == false) || ((last != nil) && (item.Less(last) || item.Equal(last))) {
+			t.Fatalf("%v out of range or order", item)
+		}
+		last = item
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBTreeSetAbortAfterSplit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "btree")
+	set, err := OpenBTreeSet(path, Padded(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := EqualSet{Padded(0), Padded(1)}
+	if err := set.AddAll(expected); err != nil {
+		t.Fatal(err)
+	}
+	batch := EqualSet{}
+	for i := 2; i < 40; i++ {
+		batch = append(batch, Padded(i))
+	}
+	if err := set.AddAll(append(batch, Int(0))); err == nil {
+		t.Fatal("added a mistyped item")
+	}
+	checkBTreeSet(t, set, expected)
+	if err := set.AddAll(batch); err != nil {
+		t.Fatal(err)
+	}
+	expected = append(expected, batch...)
+	checkBTreeSet(t, set, expected)
+	set.Close()
+	set, err = OpenBTreeSet(path, Padded(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer set.Close()
+	checkBTreeSet(t, set, expected)
+}
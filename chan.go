@@ -0,0 +1,181 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// The InsertMode decides whether a channel function keeps every received item or only the first of each group of equal items.
+type InsertMode int
+
+const (
+	KeepDuplicates InsertMode = iota
+	// Items already seen are skipped. Hashable items are checked by hash, others with Has.
+	Deduplicate
+)
+
+// Receives items from the channel into a new set until the channel is closed or the context is done. Check ctx.Err to know if the returned set is incomplete. The mode defaults to KeepDuplicates.
+func FromChan(ctx context.Context, from <-chan Comparable, mode ...InsertMode) EqualSet {
+	if asserting {
+		if ctx == nil {
+			panic("unordered: nil context")
+		}
+		if from == nil {
+			panic("unordered: nil channel")
+		}
+	}
+	c := newCollector(mode)
+	for {
+		select {
+		case item, ok := <-from:
+			if ok == false {
+				return c.set
+			}
+			c.add(item)
+		case <-ctx.Done():
+			return c.set
+		}
+	}
+}
+
+// Receives items from the channel into a new set until the channel is closed. The mode defaults to KeepDuplicates.
+func Collect(from <-chan Comparable, mode ...InsertMode) EqualSet {
+	return FromChan(context.Background(), from, mode...)
+}
+
+// Sends the items of the set on the returned channel, which is closed after the last item or when the context is done. With Deduplicate each group of equal items is sent once.
+func ToChan(ctx context.Context, the EqualSet, mode ...InsertMode) <-chan Comparable {
+	if asserting {
+		if ctx == nil {
+			panic("unordered: nil context")
+		}
+		if the == nil {
+			panic("unordered: nil set")
+		}
+	}
+	out := make(chan Comparable)
+	go func() {
+		defer close(out)
+		var seen *index
+		if insertMode(mode) == Deduplicate {
+			seen = newIndex()
+		}
+		for _, item := range the {
+			if seen != nil {
+				if seen.has(item) {
+					continue
+				}
+				seen.add(item)
+			}
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// A Sink accumulates items sent by producers on its channel into a set in its own goroutine.
+type Sink struct {
+	in   chan Comparable
+	out  chan EqualSet
+	once sync.Once
+	set  EqualSet
+}
+
+// Starts a Sink with the channel buffer size. The sink stops accumulating when its channel is closed or the context is done. After the context is done items sent on the channel are received and discarded so producers don't block, and the channel must still be closed to stop the sink's goroutine. The mode defaults to KeepDuplicates.
+func NewSink(ctx context.Context, buffer int, mode ...InsertMode) *Sink {
+	if asserting {
+		if ctx == nil {
+			panic("unordered: nil context")
+		}
+		if buffer < 0 {
+			panic("unordered: negative buffer size")
+		}
+	}
+	s := &Sink{
+		in:  make(chan Comparable, buffer),
+		out: make(chan EqualSet, 1),
+	}
+	go func() {
+		s.out <- FromChan(ctx, s.in, mode...)
+		for range s.in {
+		}
+	}()
+	return s
+}
+
+// The channel producers send items on. Close it when all producers are done.
+func (a *Sink) C() chan<- Comparable {
+	return a.in
+}
+
+// Waits for the sink to stop and returns the accumulated set. Subsequent calls return the same set.
+func (a *Sink) Set() EqualSet {
+	a.once.Do(func() {
+		a.set = <-a.out
+	})
+	return a.set
+}
+
+func insertMode(the []InsertMode) InsertMode {
+	if asserting {
+		if len(the) > 1 {
+			panic("unordered: more than one InsertMode")
+		}
+	}
+	if len(the) == 0 {
+		return KeepDuplicates
+	}
+	return the[0]
+}
+
+// A collector appends items without copying the set on every Add.
+type collector struct {
+	set  EqualSet
+	seen *index
+	t    reflect.Type
+}
+
+func newCollector(mode []InsertMode) *collector {
+	c := &collector{
+		set: make(EqualSet, 0),
+	}
+	if insertMode(mode) == Deduplicate {
+		c.seen = newIndex()
+	}
+	return c
+}
+
+func (a *collector) add(the Comparable) {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil Comparable received")
+		}
+		t := reflect.TypeOf(the)
+		if a.t == nil {
+			a.t = t
+		} else if a.t != t {
+			panic(fmt.Sprintf("unordered: set type %v doesn't match new item (%v) type %v", a.t, the, t))
+		}
+	}
+	if a.seen != nil {
+		if a.seen.has(the) {
+			return
+		}
+		a.seen.add(the)
+	}
+	a.set = append(a.set, the)
+}
@@ -0,0 +1,112 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func sendAll(the EqualSet) <-chan Comparable {
+	out := make(chan Comparable)
+	go func() {
+		for _, item := range the {
+			out <- item
+		}
+		close(out)
+	}()
+	return out
+}
+
+type ChanCase struct {
+	In   EqualSet
+	Mode InsertMode
+	Out  EqualSet
+}
+
+var ChanCases = []ChanCase{
+	{
+		In:   EqualSet{Int(1), Int(2), Int(1), Int(3)},
+		Mode: KeepDuplicates,
+		Out:  EqualSet{Int(3), Int(1), Int(2), Int(1)},
+	},
+	{
+		In:   EqualSet{Int(1), Int(2), Int(1), Int(3)},
+		Mode: Deduplicate,
+		Out:  EqualSet{Int(3), Int(1), Int(2)},
+	},
+	{
+		In:   EqualSet{Coordinate{0, 1}, Coordinate{0, 1}, Coordinate{1, 0}},
+		Mode: Deduplicate,
+		Out:  EqualSet{Coordinate{1, 0}, Coordinate{0, 1}},
+	},
+}
+
+func TestCollect(t *testing.T) {
+	for i, c := range ChanCases {
+		if Collect(sendAll(c.In), c.Mode).Equal(c.Out) == false {
+			t.Fatalf("%v: Collect failed", i)
+		}
+		if Collect(ToChan(context.Background(), c.In, c.Mode)).Equal(c.Out) == false {
+			t.Fatalf("%v: ToChan failed", i)
+		}
+	}
+}
+
+func TestFromChanCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan Comparable)
+	go func() {
+		in <- Int(1)
+		cancel()
+	}()
+	out := FromChan(ctx, in)
+	if ctx.Err() == nil {
+		t.Fatal("context not canceled")
+	}
+	if len(out) > 1 {
+		t.Fatal("more than one item received")
+	}
+}
+
+func TestSink(t *testing.T) {
+	for i, c := range ChanCases {
+		s := NewSink(context.Background(), 2, c.Mode)
+		var wait sync.WaitGroup
+		for _, item := range c.In {
+			wait.Add(1)
+			go func(the Comparable) {
+				s.C() <- the
+				wait.Done()
+			}(item)
+		}
+		wait.Wait()
+		close(s.C())
+		if s.Set().Equal(c.Out) == false {
+			t.Fatalf("%v failed", i)
+		}
+	}
+}
+
+func TestSinkCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewSink(ctx, 0)
+	s.C() <- Int(1)
+	cancel()
+	s.Set()
+	// producers sending after cancel don't block
+	for i := 0; i < 10; i++ {
+		s.C() <- Int(2)
+	}
+	close(s.C())
+	if s.Set().Has(Int(2)) {
+		t.Fatal("item sent after cancel accumulated")
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"math"
+	"sort"
+)
+
+// A CountMinSketch is an approximate multiset that counts copies of items in a fixed amount of memory, instead of keeping every copy like EqualSet. Count never underestimates, and overestimates by at most epsilon times Total with probability 1 - delta, for the epsilon and delta the sketch was created with. Items can't be listed; HeavyHitters keeps the most counted items.
+//
+// Counters are chosen by the item's digest. The zero value can't be used; a sketch is created by NewCountMinSketch or NewCountMinSketchFrom.
+type CountMinSketch struct {
+	rows  [][]uint64
+	total uint64
+}
+
+// Creates an empty sketch with error at most epsilon times Total with probability 1 - delta. The sketch has ceil(e / epsilon) counters in each of ceil(ln(1 / delta)) rows.
+func NewCountMinSketch(epsilon, delta float64) *CountMinSketch {
+	if asserting {
+		if (epsilon <= 0) || (epsilon >= 1) {
+			panic("unordered: epsilon not between 0 and 1")
+		}
+		if (delta <= 0) || (delta >= 1) {
+			panic("unordered: delta not between 0 and 1")
+		}
+	}
+	width := int(math.Ceil(math.E / epsilon))
+	depth := int(math.Ceil(math.Log(1 / delta)))
+	rows := make([][]uint64, depth)
+	for i := range rows {
+		rows[i] = make([]uint64, width)
+	}
+	return &CountMinSketch{rows: rows}
+}
+
+// Creates a sketch with the counts of the items in the set.
+func NewCountMinSketchFrom(set EqualSet, epsilon, delta float64) (*CountMinSketch, error) {
+	out := NewCountMinSketch(epsilon, delta)
+	for _, item := range set {
+		err := out.Add(item, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Adds n copies of the item. An error is returned if the item is neither Hashable nor an encoding.BinaryMarshaler.
+func (a *CountMinSketch) Add(the Comparable, n uint64) error {
+	if asserting {
+		if len(a.rows) == 0 {
+			panic("unordered: CountMinSketch not created with NewCountMinSketch")
+		}
+	}
+	d, err := digest(the)
+	if err != nil {
+		return err
+	}
+	a.add(d, n)
+	return nil
+}
+
+func (a *CountMinSketch) add(d, n uint64) {
+	h1, h2 := d, mix(d)|1
+	width := uint64(len(a.rows[0]))
+	for i, row := range a.rows {
+		row[(h1+uint64(i)*h2)%width] += n
+	}
+	a.total += n
+}
+
+// Returns an upper bound of the count of copies of the item added.
+func (a *CountMinSketch) Count(the Comparable) uint64 {
+	if asserting {
+		if len(a.rows) == 0 {
+			panic("unordered: CountMinSketch not created with NewCountMinSketch")
+		}
+	}
+	d, err := digest(the)
+	if err != nil {
+		return 0
+	}
+	return a.count(d)
+}
+
+func (a *CountMinSketch) count(d uint64) uint64 {
+	h1, h2 := d, mix(d)|1
+	width := uint64(len(a.rows[0]))
+	out := uint64(math.MaxUint64)
+	for i, row := range a.rows {
+		c := row[(h1+uint64(i)*h2)%width]
+		if c < out {
+			out = c
+		}
+	}
+	return out
+}
+
+// Returns the sum of n of all Add calls, including those of merged sketches.
+func (a *CountMinSketch) Total() uint64 {
+	return a.total
+}
+
+// Adds the counts of the other sketch to this one. The sketches must have been created with the same epsilon and delta, otherwise ErrIncompatible is returned.
+func (a *CountMinSketch) Merge(with *CountMinSketch) error {
+	if (len(a.rows) != len(with.rows)) || (len(a.rows[0]) != len(with.rows[0])) {
+		return ErrIncompatible
+	}
+	for i, row := range with.rows {
+		for j, c := range row {
+			a.rows[i][j] += c
+		}
+	}
+	a.total += with.total
+	return nil
+}
+
+// HeavyHitters is a CountMinSketch that also keeps the k items with the largest estimated counts. Items with equal estimated counts are ordered by their digest, so the top items don't depend on map iteration order. The zero value can't be used; one is created by NewHeavyHitters.
+type HeavyHitters struct {
+	sketch *CountMinSketch
+	k      int
+	top    map[uint64]Comparable
+}
+
+// Creates an empty CountMinSketch that keeps the top k items.
+func NewHeavyHitters(k int, epsilon, delta float64) *HeavyHitters {
+	if asserting {
+		if k < 1 {
+			panic("unordered: k less than 1")
+		}
+	}
+	return &HeavyHitters{
+		sketch: NewCountMinSketch(epsilon, delta),
+		k:      k,
+		top:    make(map[uint64]Comparable, k+1),
+	}
+}
+
+// Adds n copies of the item, replacing the least counted top item with it if its estimated count is now larger. Replacing the least counted item takes O(k) time.
+func (a *HeavyHitters) Add(the Comparable, n uint64) error {
+	if asserting {
+		if a.sketch == nil {
+			panic("unordered: HeavyHitters not created with NewHeavyHitters")
+		}
+	}
+	d, err := digest(the)
+	if err != nil {
+		return err
+	}
+	a.sketch.add(d, n)
+	a.consider(d, the)
+	return nil
+}
+
+// Returns the estimated count of copies of the item, like CountMinSketch.Count.
+func (a *HeavyHitters) Count(the Comparable) uint64 {
+	if asserting {
+		if a.sketch == nil {
+			panic("unordered: HeavyHitters not created with NewHeavyHitters")
+		}
+	}
+	return a.sketch.Count(the)
+}
+
+// Returns the count of all copies added.
+func (a *HeavyHitters) Total() uint64 {
+	return a.sketch.Total()
+}
+
+// If the first of two top items with their estimated counts ranks higher, the larger count or the smaller digest of equal counts, then true is returned.
+func ranksHigher(d, count, than, thanCount uint64) bool {
+	if count != thanCount {
+		return count > thanCount
+	}
+	return d < than
+}
+
+func (a *HeavyHitters) consider(d uint64, the Comparable) {
+	if _, has := a.top[d]; has {
+		return
+	}
+	if len(a.top) < a.k {
+		a.top[d] = the
+		return
+	}
+	c := a.sketch.count(d)
+	least, leastCount, first := uint64(0), uint64(0), true
+	for t := range a.top {
+		tc := a.sketch.count(t)
+		if first || ranksHigher(least, leastCount, t, tc) {
+			least, leastCount, first = t, tc, false
+		}
+	}
+	if ranksHigher(d, c, least, leastCount) {
+		delete(a.top, least)
+		a.top[d] = the
+	}
+}
+
+// Adds the counts of the other sketch to this one and reconsiders the top items of both. The sketches must have been created with the same k, epsilon, and delta, otherwise ErrIncompatible is returned.
+func (a *HeavyHitters) Merge(with *HeavyHitters) error {
+	if a.k != with.k {
+		return ErrIncompatible
+	}
+	err := a.sketch.Merge(with.sketch)
+	if err != nil {
+		return err
+	}
+	for d, item := range with.top {
+		a.consider(d, item)
+	}
+	return nil
+}
+
+// Returns at most k items with the largest estimated counts, ordered from the largest count.
+func (a *HeavyHitters) Top() EqualSet {
+	type entry struct {
+		item  Comparable
+		d     uint64
+		count uint64
+	}
+	entries := make([]entry, 0, len(a.top))
+	for d, item := range a.top {
+		entries = append(entries, entry{item, d, a.sketch.count(d)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return ranksHigher(entries[i].d, entries[i].count, entries[j].d, entries[j].count)
+	})
+	out := make(EqualSet, len(entries))
+	for i, e := range entries {
+		out[i] = e.item
+	}
+	return out
+}
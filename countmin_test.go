@@ -0,0 +1,134 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestCountMinSketch(t *testing.T) {
+	const epsilon, delta = 0.001, 0.01
+	r := rand.New(rand.NewSource(4))
+	z := rand.NewZipf(r, 1.2, 1, 100000)
+	exact := make(map[Int]uint64)
+	a := NewCountMinSketch(epsilon, delta)
+	b := NewCountMinSketch(epsilon, delta)
+	for i := 0; i < 200000; i++ {
+		item := Int(z.Uint64())
+		n := uint64(1 + r.Intn(3))
+		exact[item] += n
+		into := a
+		if i%2 == 1 {
+			into = b
+		}
+		err := into.Add(item, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := a.Merge(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total uint64
+	for _, n := range exact {
+		total += n
+	}
+	if a.Total() != total {
+		t.Fatal(a.Total(), total)
+	}
+	bound := uint64(epsilon * float64(total))
+	over := 0
+	for item, n := range exact {
+		c := a.Count(item)
+		if c < n {
+			t.Fatalf("count %v of %v is less than %v", c, item, n)
+		}
+		if c-n > bound {
+			over++
+		}
+	}
+	if float64(over) > delta*float64(len(exact)) {
+		t.Fatalf("%v of %v counts exceed the error bound %v", over, len(exact), bound)
+	}
+	if a.Merge(NewCountMinSketch(0.01, delta)) != ErrIncompatible {
+		t.Fatal("merged sketches of different sizes")
+	}
+}
+
+func TestHeavyHitters(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	z := rand.NewZipf(r, 1.5, 1, 10000)
+	shards := []*HeavyHitters{NewHeavyHitters(5, 0.001, 0.01), NewHeavyHitters(5, 0.001, 0.01)}
+	for i := 0; i < 100000; i++ {
+		err := shards[i%2].Add(Coordinate{int(z.Uint64()), 0}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := shards[0].Merge(shards[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	top := shards[0].Top()
+	// the Zipf distribution's most frequent values are its smallest
+	expected := EqualSet{Coordinate{0, 0}, Coordinate{1, 0}, Coordinate{2, 0}, Coordinate{3, 0}, Coordinate{4, 0}}
+	if top.Equal(expected) == false {
+		t.Fatal(top)
+	}
+	if top[0].Equal(Coordinate{0, 0}) == false {
+		t.Fatal("top not ordered by count", top)
+	}
+	if shards[0].Merge(NewHeavyHitters(3, 0.001, 0.01)) != ErrIncompatible {
+		t.Fatal("merged different k")
+	}
+	if (shards[0].Count(Coordinate{0, 0}) < shards[0].Count(Coordinate{4, 0})) || (shards[0].Total() != 100000) {
+		t.Fatal("unexpected counts")
+	}
+	// tied counts are ordered the same way every time
+	var first EqualSet
+	for i := 0; i < 10; i++ {
+		tied := NewHeavyHitters(3, 0.001, 0.01)
+		for j := 0; j < 10; j++ {
+			tied.Add(Int(j), 1)
+		}
+		top := tied.Top()
+		if first == nil {
+			first = top
+		}
+		for j := range top {
+			if top[j] != first[j] {
+				t.Fatalf("tied top %v, then %v", first, top)
+			}
+		}
+	}
+}
+
+func TestCountMinSketchZeroValue(t *testing.T) {
+	for _, add := range []func(){
+		func() {
+			var zero CountMinSketch
+			zero.Add(Int(1), 1)
+		},
+		func() {
+			var zero HeavyHitters
+			zero.Add(Int(1), 1)
+		},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("zero value sketch used")
+				}
+			}()
+			add()
+		}()
+	}
+}
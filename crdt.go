@@ -0,0 +1,286 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+// A GSet is a grow-only replicated set. Items can be added but never removed.
+//
+// The conflict-free replicated set types GSet, TwoPSet, and ORSet can be changed independently by replicas that are offline from each other, and converge to the same set when their states are merged in any order, any number of times. Each type offers two ways to replicate: send the whole state and Merge it, or send only the changes since the last export with Delta, which is a state of the same type that is merged the same way.
+type GSet struct {
+	items EqualSet
+	delta EqualSet
+}
+
+// Creates an empty GSet.
+func NewGSet() *GSet {
+	return &GSet{
+		items: make(EqualSet, 0),
+		delta: make(EqualSet, 0),
+	}
+}
+
+// Adds an item. Adding an item the set has does nothing.
+func (a *GSet) Add(the Comparable) {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	if a.items.Has(the) {
+		return
+	}
+	a.items = append(a.items, the)
+	a.delta = append(a.delta, the)
+}
+
+// If the set has the item then true is returned.
+func (a *GSet) Has(the Comparable) bool {
+	return a.items.Has(the)
+}
+
+// Merges the state of another replica into this one, as the union of both.
+func (a *GSet) Merge(from *GSet) {
+	for _, item := range from.items {
+		if a.items.Has(item) == false {
+			a.items = append(a.items, item)
+		}
+	}
+}
+
+// Returns the items added since the last call to Delta, as a GSet to Merge into other replicas. Items merged from other replicas aren't included.
+func (a *GSet) Delta() *GSet {
+	out := &GSet{
+		items: a.delta,
+		delta: make(EqualSet, 0),
+	}
+	a.delta = make(EqualSet, 0)
+	return out
+}
+
+// Returns a copy of the state.
+func (a *GSet) Copy() *GSet {
+	return &GSet{
+		items: append(EqualSet{}, a.items...),
+		delta: append(EqualSet{}, a.delta...),
+	}
+}
+
+// Returns the items as an EqualSet without duplicates.
+func (a *GSet) EqualSet() EqualSet {
+	return append(EqualSet{}, a.items...)
+}
+
+// A TwoPSet is a two-phase replicated set. An item can be added and then removed, but once removed it can't be added again. Removal wins over a concurrent add.
+type TwoPSet struct {
+	added   *GSet
+	removed *GSet
+}
+
+// Creates an empty TwoPSet.
+func NewTwoPSet() *TwoPSet {
+	return &TwoPSet{
+		added:   NewGSet(),
+		removed: NewGSet(),
+	}
+}
+
+// Adds an item. Adding a removed item does nothing.
+func (a *TwoPSet) Add(the Comparable) {
+	a.added.Add(the)
+}
+
+// Removes an item permanently. Removing an item the set doesn't have does nothing.
+func (a *TwoPSet) Remove(the Comparable) {
+	if a.Has(the) {
+		a.removed.Add(the)
+	}
+}
+
+// If the item was added and not removed then true is returned.
+func (a *TwoPSet) Has(the Comparable) bool {
+	return a.added.Has(the) && (a.removed.Has(the) == false)
+}
+
+// Merges the state of another replica into this one.
+func (a *TwoPSet) Merge(from *TwoPSet) {
+	a.added.Merge(from.added)
+	a.removed.Merge(from.removed)
+}
+
+// Returns the adds and removes since the last call to Delta, as a TwoPSet to Merge into other replicas. Changes merged from other replicas aren't included.
+func (a *TwoPSet) Delta() *TwoPSet {
+	return &TwoPSet{
+		added:   a.added.Delta(),
+		removed: a.removed.Delta(),
+	}
+}
+
+// Returns a copy of the state.
+func (a *TwoPSet) Copy() *TwoPSet {
+	return &TwoPSet{
+		added:   a.added.Copy(),
+		removed: a.removed.Copy(),
+	}
+}
+
+// Returns the items added and not removed as an EqualSet without duplicates.
+func (a *TwoPSet) EqualSet() EqualSet {
+	out := make(EqualSet, 0, len(a.added.items))
+	for _, item := range a.added.items {
+		if a.removed.Has(item) == false {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// A Tag uniquely identifies one Add to an ORSet: the replica that made it and the count of tags that replica had made.
+type Tag struct {
+	Replica string
+	Counter uint64
+}
+
+// An ORSet is an observed-remove replicated set. Each Add tags the item with a new unique Tag, and Remove removes only the tags the replica has observed, so an add concurrent with a remove wins and items can be added again after removal.
+//
+// Removed tags are kept as tombstones so merging an older state doesn't bring the item back.
+type ORSet struct {
+	replica    string
+	counter    uint64
+	entries    []orEntry
+	tombstones map[Tag]struct{}
+	delta      *ORSet
+}
+
+type orEntry struct {
+	item Comparable
+	tags map[Tag]struct{}
+}
+
+// Creates an empty ORSet for the replica, which must have a name unique among all replicas of the set.
+func NewORSet(replica string) *ORSet {
+	if asserting {
+		if replica == "" {
+			panic("unordered: empty replica name")
+		}
+	}
+	return &ORSet{
+		replica:    replica,
+		entries:    make([]orEntry, 0),
+		tombstones: make(map[Tag]struct{}),
+	}
+}
+
+// Adds an item with a new tag.
+func (a *ORSet) Add(the Comparable) {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	a.counter++
+	tag := Tag{Replica: a.replica, Counter: a.counter}
+	a.entry(the).tags[tag] = struct{}{}
+	a.deltas().entry(the).tags[tag] = struct{}{}
+}
+
+// Removes the item by removing every tag of it this replica has observed.
+func (a *ORSet) Remove(the Comparable) {
+	i := a.find(the)
+	if i < 0 {
+		return
+	}
+	d := a.deltas()
+	for tag := range a.entries[i].tags {
+		a.tombstones[tag] = struct{}{}
+		d.tombstones[tag] = struct{}{}
+	}
+	a.entries[i].tags = make(map[Tag]struct{})
+}
+
+// If the item has a tag that isn't removed then true is returned.
+func (a *ORSet) Has(the Comparable) bool {
+	i := a.find(the)
+	return (i >= 0) && (len(a.entries[i].tags) > 0)
+}
+
+// Merges the state of another replica into this one, as the union of the tags and tombstones of both.
+func (a *ORSet) Merge(from *ORSet) {
+	for tag := range from.tombstones {
+		a.tombstones[tag] = struct{}{}
+	}
+	for _, e := range from.entries {
+		if len(e.tags) == 0 {
+			continue
+		}
+		mine := a.entry(e.item)
+		for tag := range e.tags {
+			mine.tags[tag] = struct{}{}
+		}
+	}
+	for i := range a.entries {
+		for tag := range a.entries[i].tags {
+			if _, removed := a.tombstones[tag]; removed {
+				delete(a.entries[i].tags, tag)
+			}
+		}
+	}
+}
+
+// Returns the tags added and removed since the last call to Delta, as an ORSet to Merge into other replicas. Changes merged from other replicas aren't included.
+func (a *ORSet) Delta() *ORSet {
+	out := a.deltas()
+	a.delta = nil
+	return out
+}
+
+// Returns a copy of the state.
+func (a *ORSet) Copy() *ORSet {
+	out := NewORSet(a.replica)
+	out.counter = a.counter
+	out.Merge(a)
+	if a.delta != nil {
+		out.delta = a.delta.Copy()
+	}
+	return out
+}
+
+// Returns the items with tags that aren't removed as an EqualSet without duplicates.
+func (a *ORSet) EqualSet() EqualSet {
+	out := make(EqualSet, 0, len(a.entries))
+	for _, e := range a.entries {
+		if len(e.tags) > 0 {
+			out = append(out, e.item)
+		}
+	}
+	return out
+}
+
+func (a *ORSet) deltas() *ORSet {
+	if a.delta == nil {
+		a.delta = NewORSet(a.replica)
+	}
+	return a.delta
+}
+
+func (a *ORSet) find(the Comparable) int {
+	for i, e := range a.entries {
+		if e.item.Equal(the) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (a *ORSet) entry(the Comparable) *orEntry {
+	i := a.find(the)
+	if i < 0 {
+		a.entries = append(a.entries, orEntry{item: the, tags: make(map[Tag]struct{})})
+		i = len(a.entries) - 1
+	}
+	return &a.entries[i]
+}
@@ -0,0 +1,183 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// replica adapts the replicated set types to one property test.
+type replica struct {
+	add    func(Comparable)
+	remove func(Comparable)
+	delta  func() interface{}
+	state  func() interface{}
+	merge  func(interface{})
+	view   func() EqualSet
+}
+
+func gsetReplica(string) replica {
+	s := NewGSet()
+	return replica{
+		add:    s.Add,
+		remove: func(Comparable) {},
+		delta:  func() interface{} { return s.Delta() },
+		state:  func() interface{} { return s.Copy() },
+		merge:  func(from interface{}) { s.Merge(from.(*GSet)) },
+		view:   s.EqualSet,
+	}
+}
+
+func twoPSetReplica(string) replica {
+	s := NewTwoPSet()
+	return replica{
+		add:    s.Add,
+		remove: s.Remove,
+		delta:  func() interface{} { return s.Delta() },
+		state:  func() interface{} { return s.Copy() },
+		merge:  func(from interface{}) { s.Merge(from.(*TwoPSet)) },
+		view:   s.EqualSet,
+	}
+}
+
+func orSetReplica(name string) replica {
+	s := NewORSet(name)
+	return replica{
+		add:    s.Add,
+		remove: s.Remove,
+		delta:  func() interface{} { return s.Delta() },
+		state:  func() interface{} { return s.Copy() },
+		merge:  func(from interface{}) { s.Merge(from.(*ORSet)) },
+		view:   s.EqualSet,
+	}
+}
+
+func TestCRDTConvergence(t *testing.T) {
+	for name, create := range map[string]func(string) replica{
+		"GSet":    gsetReplica,
+		"TwoPSet": twoPSetReplica,
+		"ORSet":   orSetReplica,
+	} {
+		for seed := int64(0); seed < 20; seed++ {
+			r := rand.New(rand.NewSource(seed))
+			replicas := make([]replica, 3)
+			for i := range replicas {
+				replicas[i] = create(fmt.Sprint("replica", i))
+			}
+			// messages[i] is what replica i sends to the others
+			messages := make([][]interface{}, len(replicas))
+			for step := 0; step < 300; step++ {
+				i := r.Intn(len(replicas))
+				item := Int(r.Intn(20))
+				switch r.Intn(5) {
+				case 0, 1:
+					replicas[i].add(item)
+				case 2:
+					replicas[i].remove(item)
+				case 3:
+					messages[i] = append(messages[i], replicas[i].delta())
+				case 4:
+					// partial sync of a full state between two replicas
+					j := r.Intn(len(replicas))
+					replicas[j].merge(replicas[i].state())
+				}
+			}
+			for i := range replicas {
+				messages[i] = append(messages[i], replicas[i].delta())
+			}
+			for i := range replicas {
+				inbox := make([]interface{}, 0)
+				for j := range replicas {
+					if i == j {
+						continue
+					}
+					inbox = append(inbox, messages[j]...)
+					// duplicates must have no effect
+					for k := 0; k < 3; k++ {
+						if len(messages[j]) > 0 {
+							inbox = append(inbox, messages[j][r.Intn(len(messages[j]))])
+						}
+					}
+				}
+				r.Shuffle(len(inbox), func(a, b int) {
+					inbox[a], inbox[b] = inbox[b], inbox[a]
+				})
+				for _, m := range inbox {
+					replicas[i].merge(m)
+				}
+			}
+			for i := 1; i < len(replicas); i++ {
+				if replicas[i].view().Equal(replicas[0].view()) == false {
+					t.Fatalf("%v seed %v: replica %v %v doesn't match replica 0 %v", name, seed, i, replicas[i].view(), replicas[0].view())
+				}
+			}
+		}
+	}
+}
+
+func TestCRDTMergeProperties(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	a := NewORSet("a")
+	b := NewORSet("b")
+	for i := 0; i < 100; i++ {
+		s := a
+		if r.Intn(2) == 0 {
+			s = b
+		}
+		if r.Intn(3) == 0 {
+			s.Remove(Int(r.Intn(10)))
+		} else {
+			s.Add(Int(r.Intn(10)))
+		}
+	}
+	ab := a.Copy()
+	ab.Merge(b)
+	ba := b.Copy()
+	ba.Merge(a)
+	if ab.EqualSet().Equal(ba.EqualSet()) == false {
+		t.Fatal("Merge not commutative")
+	}
+	ab.Merge(b)
+	ab.Merge(a)
+	if ab.EqualSet().Equal(ba.EqualSet()) == false {
+		t.Fatal("Merge not idempotent")
+	}
+}
+
+func TestCRDTSemantics(t *testing.T) {
+	twoP := NewTwoPSet()
+	twoP.Add(Int(1))
+	twoP.Remove(Int(1))
+	twoP.Add(Int(1))
+	if twoP.Has(Int(1)) {
+		t.Fatal("removed TwoPSet item added again")
+	}
+	a := NewORSet("a")
+	a.Add(Int(1))
+	b := a.Copy()
+	b.replica = "b"
+	a.Remove(Int(1))
+	b.Add(Int(1))
+	a.Merge(b)
+	b.Merge(a)
+	if (a.Has(Int(1)) == false) || (b.Has(Int(1)) == false) {
+		t.Fatal("concurrent ORSet add didn't win over remove")
+	}
+	a.Remove(Int(1))
+	b.Merge(a.Delta())
+	if b.Has(Int(1)) {
+		t.Fatal("observed ORSet remove not replicated")
+	}
+	a.Add(Int(1))
+	if a.Has(Int(1)) == false {
+		t.Fatal("ORSet item not added again after remove")
+	}
+}
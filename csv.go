@@ -0,0 +1,419 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A RowDecoder converts one CSV record into an item.
+type RowDecoder interface {
+	DecodeRow(record []string) (Comparable, error)
+}
+
+// A RowDecoder can also be a HeaderDecoder, in which case ReadCSV gives it the first record of the input as the header instead of decoding it as an item.
+type HeaderDecoder interface {
+	DecodeHeader(record []string) error
+}
+
+// A RowEncoder converts an item into one CSV record.
+type RowEncoder interface {
+	EncodeRow(Comparable) ([]string, error)
+}
+
+// A RowEncoder can also be a HeaderEncoder, in which case WriteCSV writes its header as the first record.
+type HeaderEncoder interface {
+	Header() []string
+}
+
+// A RowDecoderFunc is a function used as a RowDecoder.
+type RowDecoderFunc func(record []string) (Comparable, error)
+
+func (a RowDecoderFunc) DecodeRow(record []string) (Comparable, error) {
+	return a(record)
+}
+
+// A RowEncoderFunc is a function used as a RowEncoder.
+type RowEncoderFunc func(Comparable) ([]string, error)
+
+func (a RowEncoderFunc) EncodeRow(the Comparable) ([]string, error) {
+	return a(the)
+}
+
+// A LineError is an error in the input of ReadCSV or ReadLines. Line counts from 1.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (a *LineError) Error() string {
+	return fmt.Sprintf("unordered: line %v: %v", a.Line, a.Err)
+}
+
+func (a *LineError) Unwrap() error {
+	return a.Err
+}
+
+// Reads CSV records into a new set, one item per record. Records are decoded as they are read so only the set is kept in memory. Errors are *LineError.
+func ReadCSV(r io.Reader, with RowDecoder) (EqualSet, error) {
+	if asserting {
+		if with == nil {
+			panic("unordered: nil RowDecoder")
+		}
+	}
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	cr.FieldsPerRecord = -1
+	out := make(EqualSet, 0)
+	var t itemType
+	header, hasHeader := with.(HeaderDecoder)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			var parse *csv.ParseError
+			if errors.As(err, &parse) {
+				return nil, &LineError{Line: parse.StartLine, Err: parse.Err}
+			}
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		if hasHeader {
+			hasHeader = false
+			err = header.DecodeHeader(record)
+			if err != nil {
+				return nil, &LineError{Line: line, Err: err}
+			}
+			continue
+		}
+		item, err := with.DecodeRow(record)
+		if err != nil {
+			return nil, &LineError{Line: line, Err: err}
+		}
+		err = t.check(item)
+		if err != nil {
+			return nil, &LineError{Line: line, Err: err}
+		}
+		out = append(out, item)
+	}
+}
+
+// Writes the items of the set as CSV records, one record per item.
+func WriteCSV(w io.Writer, the EqualSet, with RowEncoder) error {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil set")
+		}
+		if with == nil {
+			panic("unordered: nil RowEncoder")
+		}
+	}
+	cw := csv.NewWriter(w)
+	if header, ok := with.(HeaderEncoder); ok {
+		err := cw.Write(header.Header())
+		if err != nil {
+			return err
+		}
+	}
+	for _, item := range the {
+		record, err := with.EncodeRow(item)
+		if err != nil {
+			return err
+		}
+		err = cw.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// The longest line ReadLines accepts, in bytes.
+const MaxLineLength = 1 << 20
+
+// Reads one item per line into a new set, converting each line with the element parser. Leading and trailing whitespace is trimmed and blank lines are skipped. Errors are *LineError, and a line longer than MaxLineLength is an error wrapping bufio.ErrTooLong.
+func ReadLines(r io.Reader, with ElementParser) (EqualSet, error) {
+	if asserting {
+		if with == nil {
+			panic("unordered: nil ElementParser")
+		}
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 4096), MaxLineLength)
+	out := make(EqualSet, 0)
+	var t itemType
+	line := 0
+	for s.Scan() {
+		line++
+		text := strings.TrimSpace(s.Text())
+		if text == "" {
+			continue
+		}
+		item, err := with(text)
+		if err != nil {
+			return nil, &LineError{Line: line, Err: err}
+		}
+		err = t.check(item)
+		if err != nil {
+			return nil, &LineError{Line: line, Err: err}
+		}
+		out = append(out, item)
+	}
+	err := s.Err()
+	if err == bufio.ErrTooLong {
+		return nil, &LineError{Line: line + 1, Err: fmt.Errorf("longer than %v bytes: %w", MaxLineLength, err)}
+	}
+	if err != nil {
+		return nil, &LineError{Line: line + 1, Err: err}
+	}
+	return out, nil
+}
+
+// Writes one item per line, converting each item with the element formatter. A nil formatter is the same as for Format. Formatted items must not contain newlines.
+func WriteLines(w io.Writer, the EqualSet, with ElementFormatter) error {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil set")
+		}
+	}
+	if with == nil {
+		with = defaultFormatter
+	}
+	bw := bufio.NewWriter(w)
+	for _, item := range the {
+		text := with(item)
+		if strings.ContainsAny(text, "\r\n") {
+			return fmt.Errorf("unordered: formatted item %q has a newline", text)
+		}
+		bw.WriteString(text)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// An itemType remembers the type of the first item checked and reports items of a different type, for sets built from input where a mismatch is an error instead of an assertion.
+type itemType struct {
+	t reflect.Type
+}
+
+func (a *itemType) check(the Item) error {
+	if the == nil {
+		return fmt.Errorf("nil item")
+	}
+	t := reflect.TypeOf(the)
+	if a.t == nil {
+		a.t = t
+		return nil
+	}
+	if a.t != t {
+		return fmt.Errorf("item type %v doesn't match set type %v", t, a.t)
+	}
+	return nil
+}
+
+// A ColumnMap is a RowDecoder and RowEncoder for struct items that maps CSV columns to struct fields by name. It is also a HeaderDecoder and HeaderEncoder, so ReadCSV expects and WriteCSV writes a header record, and the columns of the input may be in any order. Fields may be strings, bools, numbers, or implement encoding.TextMarshaler and encoding.TextUnmarshaler. A ColumnMap must not be used by more than one ReadCSV at a time.
+type ColumnMap struct {
+	t       reflect.Type
+	columns []string
+	fields  []int // struct field index for each column
+	order   []int // field for each column of the input being decoded
+}
+
+// Creates a ColumnMap for the struct type of the sample item, which can also be a pointer to a struct. Each column name is the name of an exported field of the struct, or of a field with a csv tag of that name. With no columns every exported field is mapped in declaration order.
+func StructColumns(sample Comparable, columns ...string) (*ColumnMap, error) {
+	if asserting {
+		if sample == nil {
+			panic("unordered: nil sample")
+		}
+	}
+	t := reflect.TypeOf(sample)
+	st := t
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("unordered: %v is not a struct", t)
+	}
+	byName := make(map[string]int)
+	names := make([]string, 0, st.NumField())
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("csv"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+		byName[name] = i
+		names = append(names, name)
+	}
+	if len(columns) == 0 {
+		columns = names
+	}
+	m := &ColumnMap{
+		t:       t,
+		columns: columns,
+		fields:  make([]int, len(columns)),
+	}
+	for i, c := range columns {
+		f, has := byName[c]
+		if has == false {
+			return nil, fmt.Errorf("unordered: %v has no exported field for column %q", t, c)
+		}
+		m.fields[i] = f
+	}
+	m.order = m.fields
+	return m, nil
+}
+
+// Returns the column names.
+func (a *ColumnMap) Header() []string {
+	return a.columns
+}
+
+// Maps the columns of the input by the names in the header. Every column of the map must be present exactly once, and no name may be repeated; extra columns are ignored.
+func (a *ColumnMap) DecodeHeader(record []string) error {
+	order := make([]int, len(record))
+	matched := make([]bool, len(a.columns))
+	seen := make(map[string]struct{}, len(record))
+	for i, name := range record {
+		name = strings.TrimSpace(name)
+		if _, has := seen[name]; has {
+			return fmt.Errorf("header %q repeats column %q", record, name)
+		}
+		seen[name] = struct{}{}
+		order[i] = -1
+		for j, c := range a.columns {
+			if name == c {
+				order[i] = a.fields[j]
+				matched[j] = true
+				break
+			}
+		}
+	}
+	for j, m := range matched {
+		if m == false {
+			return fmt.Errorf("header %q doesn't have column %q", record, a.columns[j])
+		}
+	}
+	a.order = order
+	return nil
+}
+
+func (a *ColumnMap) DecodeRow(record []string) (Comparable, error) {
+	if len(record) != len(a.order) {
+		return nil, fmt.Errorf("%v fields, expected %v", len(record), len(a.order))
+	}
+	var item, v reflect.Value
+	if a.t.Kind() == reflect.Ptr {
+		item = reflect.New(a.t.Elem())
+		v = item.Elem()
+	} else {
+		v = reflect.New(a.t).Elem()
+		item = v
+	}
+	for i, field := range a.order {
+		if field < 0 {
+			continue
+		}
+		err := setField(v.Field(field), record[i])
+		if err != nil {
+			return nil, fmt.Errorf("column %v (%v): %w", i+1, v.Type().Field(field).Name, err)
+		}
+	}
+	return item.Interface().(Comparable), nil
+}
+
+func (a *ColumnMap) EncodeRow(the Comparable) ([]string, error) {
+	if reflect.TypeOf(the) != a.t {
+		return nil, fmt.Errorf("unordered: item type %v doesn't match column map type %v", reflect.TypeOf(the), a.t)
+	}
+	v := reflect.Indirect(reflect.ValueOf(the))
+	out := make([]string, len(a.fields))
+	for i, field := range a.fields {
+		var err error
+		out[i], err = formatField(v.Field(field))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func setField(v reflect.Value, text string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(text))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(strings.TrimSpace(text), 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(strings.TrimSpace(text), 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(text), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %v", v.Type())
+	}
+	return nil
+}
+
+func formatField(v reflect.Value) (string, error) {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		return string(text), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unordered: unsupported field type %v", v.Type())
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type Event struct {
+	ID     int    `csv:"id"`
+	Name   string `csv:"name"`
+	Weight float64
+	At     Pair `csv:"at"`
+}
+
+func (an Event) Equal(to Comparable) bool {
+	if an != to.(Event) {
+		return false
+	}
+	return true
+}
+
+var events = EqualSet{
+	Event{ID: 1, Name: "first, quoted", Weight: 0.5, At: Pair{1, 2}},
+	Event{ID: 2, Name: "second", Weight: 2, At: Pair{-1, 0}},
+	Event{ID: 2, Name: "second", Weight: 2, At: Pair{-1, 0}},
+}
+
+func TestCSV(t *testing.T) {
+	columns, err := StructColumns(Event{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	err = WriteCSV(&b, events, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(b.String(), "id,name,Weight,at\n1,\"first, quoted\",0.5,\"(1,2)\"\n") == false {
+		t.Fatalf("unexpected CSV %v", b.String())
+	}
+	out, err := ReadCSV(&b, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Equal(events) == false {
+		t.Fatalf("%v not equal to %v", out, events)
+	}
+}
+
+func TestCSVColumnOrder(t *testing.T) {
+	columns, err := StructColumns(Event{}, "name", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := "id,extra,name\n1,x,a\n2,y,b\n"
+	out, err := ReadCSV(strings.NewReader(in), columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Equal(EqualSet{Event{ID: 2, Name: "b"}, Event{ID: 1, Name: "a"}}) == false {
+		t.Fatalf("unexpected set %v", out)
+	}
+	_, err = StructColumns(Event{}, "missing")
+	if err == nil {
+		t.Fatal("missing column mapped")
+	}
+}
+
+type LineErrorCase struct {
+	Input string
+	Line  int
+}
+
+var CSVErrorCases = []LineErrorCase{
+	{Input: "id,name\n1,a\nx,b\n", Line: 3},
+	{Input: "id,name\n1,a\n\n2,b,c\n", Line: 4},
+	{Input: "id,name\n1,\"a\n", Line: 2},
+	{Input: "id\n1\n", Line: 1},
+	{Input: "id,id\n1,2\n", Line: 1},
+	{Input: "id,name,extra,extra\n1,a,x,y\n", Line: 1},
+}
+
+func TestCSVErrors(t *testing.T) {
+	columns, err := StructColumns(Event{}, "id", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range CSVErrorCases {
+		_, err := ReadCSV(strings.NewReader(c.Input), columns)
+		var line *LineError
+		if errors.As(err, &line) == false {
+			t.Fatalf("%v: unexpected error %v", i, err)
+		}
+		if line.Line != c.Line {
+			t.Fatalf("%v: line %v, expected %v (%v)", i, line.Line, c.Line, err)
+		}
+	}
+}
+
+func TestCSVFunc(t *testing.T) {
+	in := "1,2\n3,4\n1,2\n"
+	out, err := ReadCSV(strings.NewReader(in), RowDecoderFunc(func(record []string) (Comparable, error) {
+		return coordinateParser("(" + strings.Join(record, ",") + ")")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Equal(EqualSet{Coordinate{1, 2}, Coordinate{1, 2}, Coordinate{3, 4}}) == false {
+		t.Fatalf("unexpected set %v", out)
+	}
+}
+
+func TestLines(t *testing.T) {
+	var b bytes.Buffer
+	in := EqualSet{Coordinate{1, 2}, Coordinate{3, 4}, Coordinate{1, 2}}
+	err := WriteLines(&b, in, coordinateFormatter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "(1,2)\n(3,4)\n(1,2)\n" {
+		t.Fatalf("unexpected lines %q", b.String())
+	}
+	out, err := ReadLines(&b, coordinateParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Equal(in) == false {
+		t.Fatalf("%v not equal to %v", out, in)
+	}
+	_, err = ReadLines(strings.NewReader("(1,2)\n\n  (3,4)  \n(5)\n"), coordinateParser)
+	var line *LineError
+	if (errors.As(err, &line) == false) || (line.Line != 4) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	long := "(1,2)\n(" + strings.Repeat("1", MaxLineLength) + ",2)\n"
+	_, err = ReadLines(strings.NewReader(long), coordinateParser)
+	if (errors.Is(err, bufio.ErrTooLong) == false) || (errors.As(err, &line) == false) || (line.Line != 2) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
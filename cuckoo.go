@@ -0,0 +1,223 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// A CuckooFilter answers Has for a multiset of items like a BloomFilter, but items can also be removed. Each item is stored as a small fingerprint in one of two buckets, and Has is true for a non-added item with about the false-positive rate the filter was sized for. Unlike EqualSet the methods modify the receiver.
+//
+// Remove must only be called for added items, since removing a non-added item that shares a fingerprint with an added item removes the added item instead. Fingerprints and buckets come from the item's digest. The zero value can't be used; a filter is created by NewCuckooFilter or NewCuckooFilterFrom.
+type CuckooFilter struct {
+	buckets [][cuckooSlots]uint32
+	mask    uint64
+	bits    uint
+	count   int
+	random  uint64
+}
+
+// Returned by CuckooFilter.Add when the item can't be placed. The filter is unchanged, and no previously added item is lost.
+var ErrFilterFull = errors.New("unordered: cuckoo filter is full")
+
+const (
+	cuckooSlots = 4
+	// An item is moved between buckets at most this many times to make room before the filter is full.
+	cuckooKicks = 500
+	// Buckets are allocated for the capacity at this fraction of slots used.
+	cuckooLoad = 0.95
+)
+
+// Creates an empty CuckooFilter with room for about the capacity of items that has the false-positive rate when full.
+func NewCuckooFilter(capacity int, falsePositive float64) *CuckooFilter {
+	if asserting {
+		if capacity < 0 {
+			panic("unordered: negative capacity")
+		}
+		if (falsePositive <= 0) || (falsePositive >= 1) {
+			panic("unordered: false-positive rate not between 0 and 1")
+		}
+	}
+	n := uint64(math.Ceil(float64(capacity) / (cuckooSlots * cuckooLoad)))
+	if n < 1 {
+		n = 1
+	}
+	n = 1 << bits.Len64(n-1)
+	// a lookup compares against 2 buckets of fingerprints
+	f := uint(math.Ceil(math.Log2(2 * cuckooSlots / falsePositive)))
+	if f > 32 {
+		f = 32
+	}
+	return &CuckooFilter{
+		buckets: make([][cuckooSlots]uint32, n),
+		mask:    n - 1,
+		bits:    f,
+		random:  1,
+	}
+}
+
+// Creates a CuckooFilter sized for and holding the items of the set.
+func NewCuckooFilterFrom(set EqualSet, falsePositive float64) (*CuckooFilter, error) {
+	out := NewCuckooFilter(len(set), falsePositive)
+	for _, item := range set {
+		err := out.Add(item)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Adds the item to the filter. ErrFilterFull is returned if there's no room, and an error is returned if the item is neither Hashable nor an encoding.BinaryMarshaler.
+func (a *CuckooFilter) Add(the Comparable) error {
+	fp, i1, i2, err := a.locate(the)
+	if err != nil {
+		return err
+	}
+	if a.insert(i1, fp) || a.insert(i2, fp) {
+		a.count++
+		return nil
+	}
+	// Kick a fingerprint to its other bucket to make room, recording each swap so they can be undone if no room is found.
+	type swap struct {
+		bucket uint64
+		slot   int
+	}
+	path := make([]swap, 0, cuckooKicks)
+	i := i1
+	if a.next()&1 == 1 {
+		i = i2
+	}
+	for k := 0; k < cuckooKicks; k++ {
+		s := int(a.next() % cuckooSlots)
+		fp, a.buckets[i][s] = a.buckets[i][s], fp
+		path = append(path, swap{i, s})
+		i = a.alternate(i, fp)
+		if a.insert(i, fp) {
+			a.count++
+			return nil
+		}
+	}
+	for k := len(path) - 1; k >= 0; k-- {
+		p := path[k]
+		fp, a.buckets[p.bucket][p.slot] = a.buckets[p.bucket][p.slot], fp
+	}
+	return ErrFilterFull
+}
+
+// If the item may have been added then true is returned. If false then the item isn't in the filter.
+func (a *CuckooFilter) Has(the Comparable) bool {
+	fp, i1, i2, err := a.locate(the)
+	if err != nil {
+		return false
+	}
+	for _, i := range [2]uint64{i1, i2} {
+		for _, slot := range a.buckets[i] {
+			if slot == fp {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Removes one copy of the item. If the item's fingerprint was not found then false is returned.
+func (a *CuckooFilter) Remove(the Comparable) bool {
+	fp, i1, i2, err := a.locate(the)
+	if err != nil {
+		return false
+	}
+	for _, i := range [2]uint64{i1, i2} {
+		for s, slot := range a.buckets[i] {
+			if slot == fp {
+				a.buckets[i][s] = 0
+				a.count--
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Removes all copies of the item and returns the count removed.
+func (a *CuckooFilter) RemoveAll(the Comparable) int {
+	fp, i1, i2, err := a.locate(the)
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for k, i := range [2]uint64{i1, i2} {
+		// both indexes are the same bucket, which was already emptied of the fingerprint
+		if (k == 1) && (i1 == i2) {
+			break
+		}
+		for s, slot := range a.buckets[i] {
+			if slot == fp {
+				a.buckets[i][s] = 0
+				removed++
+			}
+		}
+	}
+	a.count -= removed
+	return removed
+}
+
+// Returns the count of items in the filter.
+func (a *CuckooFilter) Len() int {
+	return a.count
+}
+
+// Returns the count of items the filter has slots for. Adds usually start failing with ErrFilterFull above 95% of the capacity.
+func (a *CuckooFilter) Capacity() int {
+	return len(a.buckets) * cuckooSlots
+}
+
+// Returns the item's fingerprint, which is never 0 since 0 marks an empty slot, and its two bucket indices.
+func (a *CuckooFilter) locate(the Comparable) (uint32, uint64, uint64, error) {
+	if asserting {
+		if len(a.buckets) == 0 {
+			panic("unordered: CuckooFilter not created with NewCuckooFilter")
+		}
+	}
+	d, err := digest(the)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	fp := uint32(d>>32) & uint32(uint64(1)<<a.bits-1)
+	if fp == 0 {
+		fp = 1
+	}
+	i1 := d & a.mask
+	return fp, i1, a.alternate(i1, fp), nil
+}
+
+// The alternate bucket of a fingerprint is found from either of its buckets and the fingerprint alone, so moved items don't need their digest.
+func (a *CuckooFilter) alternate(i uint64, fp uint32) uint64 {
+	return (i ^ mix(uint64(fp))) & a.mask
+}
+
+func (a *CuckooFilter) insert(i uint64, fp uint32) bool {
+	for s, slot := range a.buckets[i] {
+		if slot == 0 {
+			a.buckets[i][s] = fp
+			return true
+		}
+	}
+	return false
+}
+
+// A xorshift generator chooses which fingerprint to kick, so a full filter behaves the same every run.
+func (a *CuckooFilter) next() uint64 {
+	a.random ^= a.random << 13
+	a.random ^= a.random >> 7
+	a.random ^= a.random << 17
+	return a.random
+}
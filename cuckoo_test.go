@@ -0,0 +1,131 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"testing"
+)
+
+func TestCuckooFilter(t *testing.T) {
+	const n = 10000
+	for _, p := range []float64{0.01, 0.001} {
+		f := NewCuckooFilter(n, p)
+		for i := 0; i < n; i++ {
+			err := f.Add(Int(i))
+			if err != nil {
+				t.Fatal(p, i, err)
+			}
+		}
+		positives := 0
+		const queries = 200000
+		for i := n; i < n+queries; i++ {
+			if f.Has(Int(i)) {
+				positives++
+			}
+		}
+		rate := float64(positives) / queries
+		t.Logf("target %v: empirical %.5f with %v fingerprint bits at load %.2f", p, rate, f.bits, float64(f.Len())/float64(f.Capacity()))
+		if rate > p {
+			t.Fatalf("target %v: empirical false-positive rate %v", p, rate)
+		}
+		for i := 0; i < n; i += 2 {
+			if f.Remove(Int(i)) == false {
+				t.Fatal("didn't remove", i)
+			}
+		}
+		if f.Len() != n/2 {
+			t.Fatal(f.Len())
+		}
+		for i := 1; i < n; i += 2 {
+			if f.Has(Int(i)) == false {
+				t.Fatal("lost", i)
+			}
+		}
+	}
+}
+
+func TestCuckooFilterDuplicates(t *testing.T) {
+	f, err := NewCuckooFilterFrom(EqualSet{Coordinate{1, 2}, Coordinate{1, 2}, Coordinate{1, 2}, Coordinate{3, 4}}, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Remove(Coordinate{1, 2}) == false {
+		t.Fatal("didn't remove")
+	}
+	if (f.Has(Coordinate{1, 2}) == false) || (f.Len() != 3) {
+		t.Fatal("removed all copies")
+	}
+	if f.RemoveAll(Coordinate{1, 2}) != 2 {
+		t.Fatal("didn't remove remaining copies")
+	}
+	if f.Has(Coordinate{1, 2}) || (f.Has(Coordinate{3, 4}) == false) || (f.Len() != 1) {
+		t.Fatal("RemoveAll removed the wrong items")
+	}
+	if f.Add(String("a")) == nil {
+		t.Fatal("added an item without a hash or binary encoding")
+	}
+}
+
+func TestCuckooFilterFull(t *testing.T) {
+	f := NewCuckooFilter(100, 0.01)
+	added := 0
+	for ; ; added++ {
+		err := f.Add(Int(added))
+		if err == ErrFilterFull {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if (added < f.Capacity()*9/10) || (f.Len() != added) {
+		t.Fatalf("full after %v of %v", added, f.Capacity())
+	}
+	for i := 0; i < added; i++ {
+		if f.Has(Int(i)) == false {
+			t.Fatal("lost", i, "when full")
+		}
+	}
+	// a copy of an added item can't go anywhere either, since both its buckets are full
+	for i := 0; i < 8; i++ {
+		f.Add(Int(0))
+	}
+	if f.Add(Int(0)) != ErrFilterFull {
+		t.Fatal("too many copies added")
+	}
+}
+
+func TestCuckooFilterOneBucket(t *testing.T) {
+	f := NewCuckooFilter(2, 0.01)
+	if f.Capacity() != cuckooSlots {
+		t.Fatal("more than one bucket", f.Capacity())
+	}
+	for i := 0; i < 2; i++ {
+		err := f.Add(Int(7))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if f.RemoveAll(Int(7)) != 2 {
+		t.Fatal("RemoveAll didn't remove both copies")
+	}
+	if f.Has(Int(7)) || (f.Len() != 0) {
+		t.Fatal("copies remain after RemoveAll")
+	}
+}
+
+func TestCuckooFilterZeroValue(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("zero value filter used")
+		}
+	}()
+	var zero CuckooFilter
+	zero.Add(Int(1))
+}
@@ -204,3 +204,32 @@ func setslice(the []EqualSet) []Set {
 	}
 	return out
 }
+
+// Like Remove but reuses the backing array of the set, and reports if an item was removed. Callers must own the set.
+func removeOne(from EqualSet, the Comparable) (EqualSet, bool) {
+	for i, item := range from {
+		if item.Equal(the) {
+			last := len(from) - 1
+			from[i] = from[last]
+			from[last] = nil
+			return from[:last], true
+		}
+	}
+	return from, false
+}
+
+// Like RemoveAll but reuses the backing array of the set, and returns the count of removed items. Callers must own the set.
+func removeAll(from EqualSet, the Comparable) (EqualSet, int) {
+	out := from[:0]
+	for _, item := range from {
+		if item.Equal(the) {
+			continue
+		}
+		out = append(out, item)
+	}
+	count := len(from) - len(out)
+	for i := len(out); i < len(from); i++ {
+		from[i] = nil
+	}
+	return out, count
+}
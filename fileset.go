@@ -0,0 +1,480 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A FileSet is an EqualSet kept in files so it survives restarts. Each Add, Remove, and RemoveAll is appended to a log file as a checksummed record, and the log is replayed by OpenFileSet. When the log grows past FileSetOptions.CompactAfter records the set is written to a snapshot file in a background goroutine and older logs are deleted.
+//
+// The files are named by adding suffixes to the path:
+//     path.snapshot   the set when the log generation started, with the generation number
+//     path.log.N      records of generation N
+// A log record is:
+//     length      4 bytes big-endian length of the op and item
+//     op          1 byte: 1 Add, 2 Remove, 3 RemoveAll
+//     item        the binary encoding of a Set holding the item, see Set.MarshalBinary
+//     checksum    4 bytes big-endian CRC-32 (Castagnoli) of the op and item
+// A final record that is incomplete or fails its checksum, as left by a crash during a write, is truncated away when the set is opened. A bad record followed by other records is reported as ErrCorrupt.
+//
+// Items must be registered with RegisterType. The methods are safe to call from multiple goroutines.
+type FileSet struct {
+	mutex      sync.Mutex
+	path       string
+	options    FileSetOptions
+	set        EqualSet
+	t          itemType
+	log        *os.File
+	generation uint64
+	records    int
+	size       int64
+	broken     error
+	compacting sync.WaitGroup
+	busy       bool
+	failed     error
+	closed     bool
+}
+
+// FileSetOptions change the behavior of a FileSet. The zero value has the defaults.
+type FileSetOptions struct {
+	// The count of log records that starts a background compaction. Zero means 1024, and a negative count disables automatic compaction.
+	CompactAfter int
+	// If true then the log file is synced to disk before each mutation returns. Otherwise mutations since the last sync may be lost in a crash, but the set stays consistent.
+	Sync bool
+}
+
+// ErrCorrupt is returned by OpenFileSet when a snapshot or a record that isn't the final record of the log fails its checksum.
+var ErrCorrupt = errors.New("unordered: file set is corrupt")
+
+var errClosed = errors.New("unordered: file set is closed")
+
+const (
+	addOp       = 1
+	removeOp    = 2
+	removeAllOp = 3
+)
+
+// Opens the set stored at the path, creating it if it doesn't exist. A nil options is the zero value.
+func OpenFileSet(path string, options *FileSetOptions) (*FileSet, error) {
+	a := &FileSet{
+		path: path,
+		set:  make(EqualSet, 0),
+	}
+	if options != nil {
+		a.options = *options
+	}
+	if a.options.CompactAfter == 0 {
+		a.options.CompactAfter = 1024
+	}
+	err := a.readSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	generations, err := a.logGenerations()
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range generations {
+		if g < a.generation {
+			os.Remove(a.logPath(g))
+			continue
+		}
+		err = a.replay(g)
+		if err != nil {
+			return nil, err
+		}
+		a.generation = g
+	}
+	a.log, err = os.OpenFile(a.logPath(a.generation), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+	info, err := a.log.Stat()
+	if err != nil {
+		a.log.Close()
+		return nil, err
+	}
+	a.size = info.Size()
+	return a, nil
+}
+
+// Adds an item to the set. Duplicates are allowed.
+func (a *FileSet) Add(the Comparable) error {
+	return a.apply(addOp, the)
+}
+
+// Removes one matching item. Nothing is logged if the set doesn't have the item.
+func (a *FileSet) Remove(the Comparable) error {
+	return a.apply(removeOp, the)
+}
+
+// Removes all matching items. Nothing is logged if the set doesn't have the item.
+func (a *FileSet) RemoveAll(the Comparable) error {
+	return a.apply(removeAllOp, the)
+}
+
+// Returns a copy of the current items as an EqualSet.
+func (a *FileSet) Set() EqualSet {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	out := make(EqualSet, len(a.set))
+	copy(out, a.set)
+	return out
+}
+
+// If the set has the item then true is returned.
+func (a *FileSet) Has(the Comparable) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.set.Has(the)
+}
+
+// Returns the count of items in the set.
+func (a *FileSet) Len() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return len(a.set)
+}
+
+// Writes a snapshot of the set and deletes the logs it replaces, waiting until done. An error from an earlier background compaction is returned if there was one.
+func (a *FileSet) Compact() error {
+	a.mutex.Lock()
+	if a.closed {
+		a.mutex.Unlock()
+		return errClosed
+	}
+	a.startCompaction()
+	a.mutex.Unlock()
+	a.compacting.Wait()
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	err := a.failed
+	a.failed = nil
+	return err
+}
+
+// Waits for a background compaction and closes the log file. An error from an earlier background compaction is returned if there was one.
+func (a *FileSet) Close() error {
+	a.mutex.Lock()
+	if a.closed {
+		a.mutex.Unlock()
+		return errClosed
+	}
+	a.closed = true
+	a.mutex.Unlock()
+	a.compacting.Wait()
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	err := a.log.Close()
+	if a.failed != nil {
+		return a.failed
+	}
+	return err
+}
+
+func (a *FileSet) apply(op byte, the Comparable) error {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	record, err := encodeRecord(op, the)
+	if err != nil {
+		return err
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.closed {
+		return errClosed
+	}
+	if a.broken != nil {
+		return a.broken
+	}
+	if op == addOp {
+		err = a.t.check(the)
+		if err != nil {
+			return fmt.Errorf("unordered: %w", err)
+		}
+	} else if a.set.Has(the) == false {
+		return nil
+	}
+	_, err = a.log.Write(record)
+	if (err == nil) && a.options.Sync {
+		err = a.log.Sync()
+	}
+	if err != nil {
+		// a partly written record would be followed by later records, and an unsynced one would be replayed though the op failed, so it's cut off or no more are written
+		a.repair()
+		return err
+	}
+	a.size += int64(len(record))
+	a.update(op, the)
+	a.records++
+	if (a.options.CompactAfter > 0) && (a.records >= a.options.CompactAfter) {
+		a.startCompaction()
+	}
+	return nil
+}
+
+// Cuts the log back to the end of the last record written without error. Must be called with the mutex held.
+func (a *FileSet) repair() {
+	err := a.log.Truncate(a.size)
+	if err == nil {
+		_, err = a.log.Seek(a.size, io.SeekStart)
+	}
+	if err != nil {
+		a.broken = fmt.Errorf("unordered: file set log can't be repaired after a failed write: %w", err)
+	}
+}
+
+func (a *FileSet) update(op byte, the Comparable) {
+	switch op {
+	case addOp:
+		a.set = append(a.set, the)
+	case removeOp:
+		a.set, _ = removeOne(a.set, the)
+	case removeAllOp:
+		a.set, _ = removeAll(a.set, the)
+	}
+}
+
+// Must be called with the mutex held. The log is switched to the next generation immediately, and the snapshot of the previous generations is written in the background.
+func (a *FileSet) startCompaction() {
+	if a.busy {
+		return
+	}
+	next, err := os.OpenFile(a.logPath(a.generation+1), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		a.failed = err
+		return
+	}
+	a.log.Close()
+	a.log = next
+	a.generation++
+	a.records = 0
+	a.size = 0
+	a.busy = true
+	snapshot := make(EqualSet, len(a.set))
+	copy(snapshot, a.set)
+	generation := a.generation
+	a.compacting.Add(1)
+	go func() {
+		defer a.compacting.Done()
+		err := a.writeSnapshot(generation, snapshot)
+		a.mutex.Lock()
+		a.busy = false
+		if err != nil {
+			a.failed = err
+		}
+		a.mutex.Unlock()
+		if err != nil {
+			return
+		}
+		generations, _ := a.logGenerations()
+		for _, g := range generations {
+			if g < generation {
+				os.Remove(a.logPath(g))
+			}
+		}
+	}()
+}
+
+func (a *FileSet) logPath(generation uint64) string {
+	return a.path + ".log." + strconv.FormatUint(generation, 10)
+}
+
+func (a *FileSet) snapshotPath() string {
+	return a.path + ".snapshot"
+}
+
+func (a *FileSet) logGenerations() ([]uint64, error) {
+	matches, err := filepath.Glob(a.path + ".log.*")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]uint64, 0, len(matches))
+	for _, m := range matches {
+		g, err := strconv.ParseUint(strings.TrimPrefix(m, a.path+".log."), 10, 64)
+		if err != nil {
+			continue
+		}
+		out = append(out, g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out, nil
+}
+
+// The snapshot file is an 8 byte big-endian generation, the binary encoding of the set, and a CRC-32 (Castagnoli) of both. It's written to a temporary file that is renamed over the previous snapshot.
+func (a *FileSet) writeSnapshot(generation uint64, the EqualSet) error {
+	data, err := the.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	out := make([]byte, 8, 8+len(data)+4)
+	binary.BigEndian.PutUint64(out, generation)
+	out = append(out, data...)
+	out = binary.BigEndian.AppendUint32(out, crc32.Checksum(out, crcTable))
+	tmp := a.snapshotPath() + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(out)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	err = os.Rename(tmp, a.snapshotPath())
+	if err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(a.path))
+}
+
+func (a *FileSet) readSnapshot() error {
+	data, err := os.ReadFile(a.snapshotPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) < 12 {
+		return ErrCorrupt
+	}
+	if crc32.Checksum(data[:len(data)-4], crcTable) != binary.BigEndian.Uint32(data[len(data)-4:]) {
+		return ErrCorrupt
+	}
+	a.generation = binary.BigEndian.Uint64(data)
+	var set EqualSet
+	err = set.UnmarshalBinary(data[8 : len(data)-4])
+	if err != nil {
+		return fmt.Errorf("unordered: file set snapshot: %w", err)
+	}
+	a.set = set
+	if len(set) > 0 {
+		a.t.check(set[0])
+	}
+	return nil
+}
+
+// Applies the records of the log generation, truncating a torn final record. A bad record that doesn't reach the end of the log is ErrCorrupt.
+func (a *FileSet) replay(generation uint64) error {
+	path := a.logPath(generation)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	offset := 0
+	for offset < len(data) {
+		op, item, n, err := decodeRecord(data[offset:])
+		if err == io.ErrUnexpectedEOF {
+			// a damaged length field also looks like a record past the end, but then intact records follow it
+			if recordAfter(data[offset+1:]) {
+				return fmt.Errorf("%w: record at offset %v of %v", ErrCorrupt, offset, path)
+			}
+			return os.Truncate(path, int64(offset))
+		}
+		if err == ErrChecksum {
+			if offset+n == len(data) {
+				return os.Truncate(path, int64(offset))
+			}
+			return fmt.Errorf("%w: record at offset %v of %v", ErrCorrupt, offset, path)
+		}
+		if err != nil {
+			return fmt.Errorf("unordered: record at offset %v of %v: %w", offset, path, err)
+		}
+		err = a.t.check(item)
+		if err != nil {
+			return fmt.Errorf("unordered: record at offset %v of %v: %w", offset, path, err)
+		}
+		a.update(op, item)
+		offset += n
+	}
+	return nil
+}
+
+func encodeRecord(op byte, the Comparable) ([]byte, error) {
+	item, err := Set{the}.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 4, 4+1+len(item)+4)
+	binary.BigEndian.PutUint32(out, uint32(1+len(item)))
+	out = append(out, op)
+	out = append(out, item...)
+	return binary.BigEndian.AppendUint32(out, crc32.Checksum(out[4:], crcTable)), nil
+}
+
+// Returns the op and item of the record at the start of the data, and the length of the record. io.ErrUnexpectedEOF is returned if the record is incomplete and ErrChecksum if it's damaged.
+func decodeRecord(data []byte) (byte, Comparable, int, error) {
+	if len(data) < 4 {
+		return 0, nil, len(data), io.ErrUnexpectedEOF
+	}
+	l := int(binary.BigEndian.Uint32(data))
+	n := 4 + l + 4
+	if (l < 1) || (n > len(data)) {
+		return 0, nil, len(data), io.ErrUnexpectedEOF
+	}
+	body := data[4 : 4+l]
+	if crc32.Checksum(body, crcTable) != binary.BigEndian.Uint32(data[4+l:]) {
+		return 0, nil, n, ErrChecksum
+	}
+	op := body[0]
+	if (op < addOp) || (op > removeAllOp) {
+		return 0, nil, n, ErrFormat
+	}
+	var set EqualSet
+	err := set.UnmarshalBinary(body[1:])
+	if err != nil {
+		return 0, nil, n, err
+	}
+	if len(set) != 1 {
+		return 0, nil, n, ErrFormat
+	}
+	return op, set[0], n, nil
+}
+
+// Reports if a complete record with a valid checksum starts anywhere in the data.
+func recordAfter(data []byte) bool {
+	for i := range data {
+		_, _, _, err := decodeRecord(data[i:])
+		if (err != io.ErrUnexpectedEOF) && (err != ErrChecksum) {
+			return true
+		}
+	}
+	return false
+}
+
+func syncDir(path string) error {
+	d, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	d.Sync()
+	return nil
+}
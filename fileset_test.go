@@ -0,0 +1,181 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openFileSet(t *testing.T, path string, options *FileSetOptions) *FileSet {
+	set, err := OpenFileSet(path, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+func TestFileSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "coords")
+	set := openFileSet(t, path, nil)
+	for _, c := range []Coordinate{{0, 0}, {1, 1}, {1, 1}, {2, 2}, {1, 1}, {3, 3}} {
+		if err := set.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	set.Remove(Coordinate{0, 0})
+	set.Remove(Coordinate{9, 9})
+	set.RemoveAll(Coordinate{1, 1})
+	expected := EqualSet{Coordinate{2, 2}, Coordinate{3, 3}}
+	if set.Set().Equal(expected) == false {
+		t.Fatalf("unexpected set %v", set.Set())
+	}
+	if err := set.Add(Int(1)); err == nil {
+		t.Fatal("added item of different type")
+	}
+	if err := set.Close(); err != nil {
+		t.Fatal(err)
+	}
+	set = openFileSet(t, path, nil)
+	defer set.Close()
+	if set.Set().Equal(expected) == false {
+		t.Fatalf("reopened set %v not equal to %v", set.Set(), expected)
+	}
+}
+
+func TestFileSetTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ints")
+	set := openFileSet(t, path, nil)
+	set.Add(Int(1))
+	set.Add(Int(2))
+	set.Close()
+	record, err := encodeRecord(addOp, Int(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := path + ".log.0"
+	info, err := os.Stat(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, torn := range [][]byte{record[:3], record[:len(record)-1], append(append([]byte(nil), record[:len(record)-1]...), 0)} {
+		f, err := os.OpenFile(log, os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(torn)
+		f.Close()
+		set = openFileSet(t, path, nil)
+		if set.Set().Equal(EqualSet{Int(1), Int(2)}) == false {
+			t.Fatalf("unexpected set %v", set.Set())
+		}
+		set.Close()
+		after, err := os.Stat(log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if after.Size() != info.Size() {
+			t.Fatalf("torn record not truncated, %v bytes instead of %v", after.Size(), info.Size())
+		}
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)/4] ^= 0xff
+	os.WriteFile(log, data, 0666)
+	_, err = OpenFileSet(path, nil)
+	if errors.Is(err, ErrCorrupt) == false {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestFileSetCorruptLength(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ints")
+	set := openFileSet(t, path, nil)
+	for i := 0; i < 3; i++ {
+		set.Add(Int(i))
+	}
+	set.Close()
+	log := path + ".log.0"
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first record's length now reaches past the end of the log
+	data[1] ^= 0x40
+	os.WriteFile(log, data, 0666)
+	_, err = OpenFileSet(path, nil)
+	if errors.Is(err, ErrCorrupt) == false {
+		t.Fatalf("unexpected error %v", err)
+	}
+	info, err := os.Stat(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(data)) {
+		t.Fatalf("corrupt log truncated to %v bytes from %v", info.Size(), len(data))
+	}
+}
+
+func TestFileSetFailedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ints")
+	set := openFileSet(t, path, nil)
+	set.Add(Int(1))
+	// writes and the repairing truncate both fail on a closed file
+	set.log.Close()
+	if set.Add(Int(2)) == nil {
+		t.Fatal("write to closed log succeeded")
+	}
+	if set.Add(Int(3)) == nil {
+		t.Fatal("write after a failed repair succeeded")
+	}
+	if set.Set().Equal(EqualSet{Int(1)}) == false {
+		t.Fatalf("unexpected set %v", set.Set())
+	}
+	set.Close()
+	set = openFileSet(t, path, nil)
+	defer set.Close()
+	if set.Set().Equal(EqualSet{Int(1)}) == false {
+		t.Fatalf("reopened set %v", set.Set())
+	}
+}
+
+func TestFileSetCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ints")
+	set := openFileSet(t, path, &FileSetOptions{CompactAfter: 10})
+	expected := EqualSet{}
+	for i := 0; i < 100; i++ {
+		set.Add(Int(i % 7))
+		expected = expected.Add(Int(i % 7))
+		if i%3 == 0 {
+			set.Remove(Int(i % 5))
+			expected = expected.Remove(Int(i % 5))
+		}
+	}
+	if err := set.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	set.Add(Int(100))
+	expected = expected.Add(Int(100))
+	if err := set.Close(); err != nil {
+		t.Fatal(err)
+	}
+	logs, _ := filepath.Glob(path + ".log.*")
+	if len(logs) != 1 {
+		t.Fatalf("%v logs after compaction", len(logs))
+	}
+	set = openFileSet(t, path, nil)
+	defer set.Close()
+	if set.Set().Equal(expected) == false {
+		t.Fatalf("reopened set %v not equal to %v", set.Set(), expected)
+	}
+}
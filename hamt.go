@@ -0,0 +1,401 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"fmt"
+	"math/bits"
+	"reflect"
+)
+
+// A HAMTSet is an immutable set of Hashable items stored in a hash array mapped trie. Add and Remove return a new set in O(log n) time that shares all unchanged trie nodes with the receiver, so keeping many versions of a large set is cheap. Like an EqualSet it's a multiset: each distinct item is stored once with a count of its copies.
+//
+// The zero value is an empty set. A HAMTSet can be copied and used from multiple goroutines.
+type HAMTSet struct {
+	root     *hamtNode
+	total    int
+	distinct int
+	t        reflect.Type
+}
+
+// Each level of the trie uses 5 bits of the hash. Items with equal hashes share a bucket.
+const (
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+)
+
+type hamtNode struct {
+	bitmap   uint32
+	children []interface{} // *hamtNode or *hamtBucket, in bitmap order
+	owner    *int
+}
+
+type hamtBucket struct {
+	hash   uint64
+	items  []Hashable
+	counts []int
+	owner  *int
+}
+
+// Creates a HAMTSet holding the items of the set. Every item must be Hashable. Nodes are built in place instead of copied for each item.
+func NewHAMTSet(from EqualSet) HAMTSet {
+	if asserting {
+		if from == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	owner := new(int)
+	var out HAMTSet
+	for _, item := range from {
+		out = out.add(item.(Hashable), 1, owner)
+	}
+	return out
+}
+
+// Returns the items as an EqualSet, with duplicates.
+func (a HAMTSet) EqualSet() EqualSet {
+	out := make(EqualSet, 0, a.total)
+	a.Each(func(item Hashable, count int) bool {
+		for i := 0; i < count; i++ {
+			out = append(out, item)
+		}
+		return true
+	})
+	return out
+}
+
+// Returns a new set with the item added. Duplicates are allowed.
+func (a HAMTSet) Add(the Hashable) HAMTSet {
+	return a.add(the, 1, nil)
+}
+
+// Returns a new set with one matching item removed. The receiver is returned if it doesn't have the item.
+func (a HAMTSet) Remove(the Hashable) HAMTSet {
+	return a.remove(the, false)
+}
+
+// Returns a new set with all matching items removed. The receiver is returned if it doesn't have the item.
+func (a HAMTSet) RemoveAll(the Hashable) HAMTSet {
+	return a.remove(the, true)
+}
+
+// If the set has the item then true is returned.
+func (a HAMTSet) Has(the Hashable) bool {
+	return a.Count(the) > 0
+}
+
+// Returns the count of copies of the item in the set.
+func (a HAMTSet) Count(the Hashable) int {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	hash := the.Hash()
+	n := a.root
+	for shift := uint(0); n != nil; shift += hamtBits {
+		bit := uint32(1) << ((hash >> shift) & hamtMask)
+		if n.bitmap&bit == 0 {
+			return 0
+		}
+		switch child := n.children[bits.OnesCount32(n.bitmap&(bit-1))].(type) {
+		case *hamtNode:
+			n = child
+		case *hamtBucket:
+			if child.hash != hash {
+				return 0
+			}
+			if i := child.find(the); i >= 0 {
+				return child.counts[i]
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// Returns the count of items including duplicates.
+func (a HAMTSet) Len() int {
+	return a.total
+}
+
+// Returns the count of distinct items.
+func (a HAMTSet) Distinct() int {
+	return a.distinct
+}
+
+// Calls the function with each distinct item and its count, in no particular order. Iteration stops if the function returns false.
+func (a HAMTSet) Each(fn func(item Hashable, count int) bool) {
+	if a.root != nil {
+		a.root.each(fn)
+	}
+}
+
+// If both sets contain an equal count of each item then true is returned. Subtrees shared by both sets are skipped.
+func (a HAMTSet) Equal(to HAMTSet) bool {
+	if (a.total != to.total) || (a.distinct != to.distinct) {
+		return false
+	}
+	if (a.root == to.root) || (a.distinct == 0) {
+		return true
+	}
+	// with equal totals the sets are equal if every item outside the shared subtrees has the same count in both
+	equal := true
+	a.root.eachUnshared(to.root, func(item Hashable, count int) bool {
+		if to.Count(item) != count {
+			equal = false
+		}
+		return equal
+	})
+	return equal
+}
+
+func (a HAMTSet) add(the Hashable, count int, owner *int) HAMTSet {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil arg")
+		}
+		t := reflect.TypeOf(the)
+		if (a.t != nil) && (a.t != t) {
+			panic(fmt.Sprintf("unordered: set type %v doesn't match new item (%v) type %v", a.t, the, t))
+		}
+	}
+	root := a.root
+	if root == nil {
+		root = &hamtNode{owner: owner}
+	}
+	root, added := root.add(the, the.Hash(), 0, count, owner)
+	out := HAMTSet{
+		root:     root,
+		total:    a.total + count,
+		distinct: a.distinct,
+		t:        reflect.TypeOf(the),
+	}
+	if added {
+		out.distinct++
+	}
+	return out
+}
+
+func (a HAMTSet) remove(the Hashable, all bool) HAMTSet {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	if a.root == nil {
+		return a
+	}
+	root, removed, gone := a.root.remove(the, the.Hash(), 0, all)
+	if removed == 0 {
+		return a
+	}
+	out := a
+	out.total -= removed
+	if gone {
+		out.distinct--
+	}
+	switch r := root.(type) {
+	case *hamtNode:
+		out.root = r
+	case *hamtBucket:
+		out.root = &hamtNode{bitmap: 1 << (r.hash & hamtMask), children: []interface{}{r}}
+	default:
+		out.root = nil
+	}
+	if out.total == 0 {
+		out.root = nil
+		out.t = nil
+	}
+	return out
+}
+
+// Returns the node with the item added, copying it unless the node is owned by a builder, and if the item is new to the set.
+func (n *hamtNode) add(the Hashable, hash uint64, shift uint, count int, owner *int) (*hamtNode, bool) {
+	bit := uint32(1) << ((hash >> shift) & hamtMask)
+	pos := bits.OnesCount32(n.bitmap & (bit - 1))
+	out := n.editable(owner)
+	if n.bitmap&bit == 0 {
+		b := &hamtBucket{hash: hash, items: []Hashable{the}, counts: []int{count}, owner: owner}
+		out.bitmap |= bit
+		out.children = append(out.children, nil)
+		copy(out.children[pos+1:], out.children[pos:])
+		out.children[pos] = b
+		return out, true
+	}
+	switch child := n.children[pos].(type) {
+	case *hamtNode:
+		c, added := child.add(the, hash, shift+hamtBits, count, owner)
+		out.children[pos] = c
+		return out, added
+	case *hamtBucket:
+		if child.hash == hash {
+			b := child.editable(owner)
+			if i := b.find(the); i >= 0 {
+				b.counts[i] += count
+				out.children[pos] = b
+				return out, false
+			}
+			b.items = append(b.items, the)
+			b.counts = append(b.counts, count)
+			out.children[pos] = b
+			return out, true
+		}
+		// different hashes in the same slot are separated at the next level
+		sub := &hamtNode{owner: owner}
+		sub.bitmap = uint32(1) << ((child.hash >> (shift + hamtBits)) & hamtMask)
+		sub.children = []interface{}{child}
+		sub, _ = sub.add(the, hash, shift+hamtBits, count, owner)
+		out.children[pos] = sub
+		return out, true
+	}
+	panic("unordered: invalid HAMT node")
+}
+
+// Returns what replaces the node: a node, a bucket when only one bucket is left, or nil when empty. Also returns the count of removed copies and if the item is gone from the set.
+func (n *hamtNode) remove(the Hashable, hash uint64, shift uint, all bool) (interface{}, int, bool) {
+	bit := uint32(1) << ((hash >> shift) & hamtMask)
+	if n.bitmap&bit == 0 {
+		return n, 0, false
+	}
+	pos := bits.OnesCount32(n.bitmap & (bit - 1))
+	var replacement interface{}
+	var removed int
+	var gone bool
+	switch child := n.children[pos].(type) {
+	case *hamtNode:
+		replacement, removed, gone = child.remove(the, hash, shift+hamtBits, all)
+	case *hamtBucket:
+		if child.hash != hash {
+			return n, 0, false
+		}
+		i := child.find(the)
+		if i < 0 {
+			return n, 0, false
+		}
+		if all || (child.counts[i] == 1) {
+			removed = child.counts[i]
+			gone = true
+			if len(child.items) > 1 {
+				b := &hamtBucket{hash: hash}
+				b.items = append(append(b.items, child.items[:i]...), child.items[i+1:]...)
+				b.counts = append(append(b.counts, child.counts[:i]...), child.counts[i+1:]...)
+				replacement = b
+			}
+		} else {
+			removed = 1
+			b := child.editable(nil)
+			b.counts[i]--
+			replacement = b
+		}
+	}
+	if removed == 0 {
+		return n, 0, false
+	}
+	out := n.editable(nil)
+	if replacement == nil {
+		out.bitmap &^= bit
+		out.children = append(out.children[:pos], out.children[pos+1:]...)
+	} else {
+		out.children[pos] = replacement
+	}
+	if len(out.children) == 0 {
+		return nil, removed, gone
+	}
+	if len(out.children) == 1 {
+		if b, ok := out.children[0].(*hamtBucket); ok {
+			return b, removed, gone
+		}
+	}
+	return out, removed, gone
+}
+
+func (n *hamtNode) each(fn func(Hashable, int) bool) bool {
+	for _, c := range n.children {
+		switch child := c.(type) {
+		case *hamtNode:
+			if child.each(fn) == false {
+				return false
+			}
+		case *hamtBucket:
+			for i, item := range child.items {
+				if fn(item, child.counts[i]) == false {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// Like each, but children that are the same node or bucket as the child at the same position of the other node are skipped. The other node is at the same position in another trie, or nil.
+func (n *hamtNode) eachUnshared(other *hamtNode, fn func(Hashable, int) bool) bool {
+	for i := uint(0); i <= hamtMask; i++ {
+		bit := uint32(1) << i
+		if n.bitmap&bit == 0 {
+			continue
+		}
+		c := n.children[bits.OnesCount32(n.bitmap&(bit-1))]
+		var shared interface{}
+		if (other != nil) && (other.bitmap&bit != 0) {
+			shared = other.children[bits.OnesCount32(other.bitmap&(bit-1))]
+		}
+		if c == shared {
+			continue
+		}
+		switch child := c.(type) {
+		case *hamtNode:
+			next, _ := shared.(*hamtNode)
+			if child.eachUnshared(next, fn) == false {
+				return false
+			}
+		case *hamtBucket:
+			for j, item := range child.items {
+				if fn(item, child.counts[j]) == false {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func (n *hamtNode) editable(owner *int) *hamtNode {
+	if (owner != nil) && (n.owner == owner) {
+		return n
+	}
+	out := &hamtNode{
+		bitmap:   n.bitmap,
+		children: make([]interface{}, len(n.children), len(n.children)+1),
+		owner:    owner,
+	}
+	copy(out.children, n.children)
+	return out
+}
+
+func (b *hamtBucket) editable(owner *int) *hamtBucket {
+	if (owner != nil) && (b.owner == owner) {
+		return b
+	}
+	return &hamtBucket{
+		hash:   b.hash,
+		items:  append(make([]Hashable, 0, len(b.items)+1), b.items...),
+		counts: append(make([]int, 0, len(b.counts)+1), b.counts...),
+		owner:  owner,
+	}
+}
+
+func (b *hamtBucket) find(the Hashable) int {
+	for i, item := range b.items {
+		if item.Equal(the) {
+			return i
+		}
+	}
+	return -1
+}
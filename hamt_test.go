@@ -0,0 +1,115 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Collider has few hash values so most items share a bucket or a deep trie path.
+type Collider int
+
+func (a Collider) Equal(to Comparable) bool {
+	return a == to.(Collider)
+}
+
+func (a Collider) Hash() uint64 {
+	return uint64(a%3) << 61
+}
+
+func TestHAMTSet(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for _, item := range []func(int) Hashable{
+		func(i int) Hashable { return Int(i) },
+		func(i int) Hashable { return Collider(i) },
+	} {
+		var set HAMTSet
+		expected := EqualSet{}
+		versions := make([]HAMTSet, 0, 64)
+		models := make([]EqualSet, 0, 64)
+		for i := 0; i < 2000; i++ {
+			c := item(r.Intn(500))
+			switch r.Intn(4) {
+			case 0:
+				set = set.Remove(c)
+				expected, _ = removeOne(expected, c)
+			case 1:
+				set = set.RemoveAll(c)
+				expected, _ = removeAll(expected, c)
+			default:
+				set = set.Add(c)
+				expected = append(expected, c)
+			}
+			if i%50 == 0 {
+				versions = append(versions, set)
+				models = append(models, append(EqualSet{}, expected...))
+			}
+		}
+		if set.EqualSet().Equal(expected) == false {
+			t.Fatalf("%v items not equal to %v expected", set.Len(), len(expected))
+		}
+		if set.Distinct() != len(expected.Reduce()) {
+			t.Fatalf("distinct %v, expected %v", set.Distinct(), len(expected.Reduce()))
+		}
+		for _, c := range expected {
+			if set.Count(c.(Hashable)) == 0 {
+				t.Fatalf("doesn't have %v", c)
+			}
+		}
+		for i, v := range versions {
+			if v.EqualSet().Equal(models[i]) == false {
+				t.Fatalf("version %v changed", i)
+			}
+		}
+		if NewHAMTSet(expected).Equal(set) == false {
+			t.Fatal("NewHAMTSet not equal")
+		}
+	}
+}
+
+func TestHAMTSetSharing(t *testing.T) {
+	base := EqualSet{}
+	for i := 0; i < 1000; i++ {
+		base = append(base, Int(i))
+	}
+	a := NewHAMTSet(base)
+	b := a.Add(Int(5000))
+	shared := 0
+	for i := range a.root.children {
+		if a.root.children[i] == b.root.children[i] {
+			shared++
+		}
+	}
+	if shared != len(a.root.children)-1 {
+		t.Fatalf("%v of %v root children shared", shared, len(a.root.children))
+	}
+	if a.Has(Int(5000)) || (b.Has(Int(5000)) == false) {
+		t.Fatal("Add changed the receiver")
+	}
+	if (a.Remove(Int(5000)).root != a.root) || (b.RemoveAll(Int(5000)).Equal(a) == false) {
+		t.Fatal("Remove failed")
+	}
+	c, d := a.Add(Int(1)), a.Add(Int(2))
+	visited := 0
+	c.root.eachUnshared(d.root, func(Hashable, int) bool {
+		visited++
+		return true
+	})
+	if visited >= a.Distinct()/2 {
+		t.Fatalf("Equal visited %v of %v items", visited, a.Distinct())
+	}
+	if c.Equal(d) || (c.Equal(a.Add(Int(1))) == false) {
+		t.Fatal("Equal of sets sharing subtrees")
+	}
+	var empty HAMTSet
+	if (empty.Len() != 0) || empty.Has(Int(1)) || (empty.Add(Int(1)).Remove(Int(1)).Equal(empty) == false) {
+		t.Fatal("zero value not an empty set")
+	}
+}
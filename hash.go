@@ -0,0 +1,159 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"encoding"
+	"fmt"
+	"hash/fnv"
+)
+
+// A Hashable is a Comparable that also provides a hash of itself. Items that are Equal must have the same hash. Functions in this package that look up many items use the hash when available instead of comparing against every item with Equal.
+type Hashable interface {
+	Comparable
+	Hash() uint64
+}
+
+// An index finds items in a growing collection and records a position with each item, for callers that keep their items' data in their own slice. Hashable items are found in a hash bucket, other items by comparing against every non-hashable item. The zero value is an empty index.
+type index struct {
+	buckets map[uint64][]indexed
+	linear  []indexed
+}
+
+type indexed struct {
+	item Comparable
+	at   int
+}
+
+func newIndex() *index {
+	return &index{
+		buckets: make(map[uint64][]indexed),
+		linear:  make([]indexed, 0),
+	}
+}
+
+// Returns the entries the item would be found in.
+func (an *index) entries(the Comparable) []indexed {
+	if h, ok := the.(Hashable); ok {
+		return an.buckets[h.Hash()]
+	}
+	return an.linear
+}
+
+// Returns the position recorded with the item, if it's indexed.
+func (an *index) find(the Comparable) (int, bool) {
+	for _, e := range an.entries(the) {
+		if e.item.Equal(the) {
+			return e.at, true
+		}
+	}
+	return 0, false
+}
+
+func (an *index) has(the Comparable) bool {
+	_, found := an.find(the)
+	return found
+}
+
+func (an *index) add(the Comparable) {
+	an.addAt(the, 0)
+}
+
+// Adds the item with its position. The item must not already be indexed.
+func (an *index) addAt(the Comparable, at int) {
+	if h, ok := the.(Hashable); ok {
+		if an.buckets == nil {
+			an.buckets = make(map[uint64][]indexed)
+		}
+		hash := h.Hash()
+		an.buckets[hash] = append(an.buckets[hash], indexed{the, at})
+		return
+	}
+	an.linear = append(an.linear, indexed{the, at})
+}
+
+// Changes the position recorded with an indexed item.
+func (an *index) move(the Comparable, to int) {
+	in := an.entries(the)
+	for i := range in {
+		if in[i].item.Equal(the) {
+			in[i].at = to
+			return
+		}
+	}
+	if asserting {
+		panic("unordered: moved item isn't indexed")
+	}
+}
+
+// Removes the item and reports if it was found.
+func (an *index) remove(the Comparable) bool {
+	in := an.entries(the)
+	i := 0
+	for ; i < len(in); i++ {
+		if in[i].item.Equal(the) {
+			break
+		}
+	}
+	if i == len(in) {
+		return false
+	}
+	last := len(in) - 1
+	in[i] = in[last]
+	in[last] = indexed{}
+	in = in[:last]
+	h, hashable := the.(Hashable)
+	if hashable == false {
+		an.linear = in
+	} else if len(in) == 0 {
+		delete(an.buckets, h.Hash())
+	} else {
+		an.buckets[h.Hash()] = in
+	}
+	return true
+}
+
+// Returns the items in no particular order.
+func (an *index) items() EqualSet {
+	out := make(EqualSet, 0, len(an.linear)+len(an.buckets))
+	for _, bucket := range an.buckets {
+		for _, e := range bucket {
+			out = append(out, e.item)
+		}
+	}
+	for _, e := range an.linear {
+		out = append(out, e.item)
+	}
+	return out
+}
+
+// Returns a well-mixed 64-bit digest of the item for the probabilistic filters and sketches. A Hashable item's Hash is mixed, since it may be as simple as an integer's value, otherwise the item's encoding.BinaryMarshaler bytes are hashed with FNV-1a. Equal items must have the same digest, so a BinaryMarshaler that isn't Hashable must encode Equal items identically.
+func digest(the Comparable) (uint64, error) {
+	if h, ok := the.(Hashable); ok {
+		return mix(h.Hash()), nil
+	}
+	if m, ok := the.(encoding.BinaryMarshaler); ok {
+		data, err := m.MarshalBinary()
+		if err != nil {
+			return 0, err
+		}
+		f := fnv.New64a()
+		f.Write(data)
+		return mix(f.Sum64()), nil
+	}
+	return 0, fmt.Errorf("unordered: item %v (%T) is neither Hashable nor an encoding.BinaryMarshaler", the, the)
+}
+
+// The splitmix64 finalizer.
+func mix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
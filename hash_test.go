@@ -0,0 +1,35 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"testing"
+)
+
+func (i Int) Hash() uint64 {
+	return uint64(i)
+}
+
+func TestIndex(t *testing.T) {
+	for i, c := range []EqualSet{
+		{Int(1), Int(2), Int(3)},
+		{Coordinate{1, 2}, Coordinate{2, 1}},
+	} {
+		idx := newIndex()
+		for _, item := range c {
+			if idx.has(item) {
+				t.Fatalf("%v: has %v before add", i, item)
+			}
+			idx.add(item)
+			if idx.has(item) == false {
+				t.Fatalf("%v: doesn't have %v after add", i, item)
+			}
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// A HyperLogLog estimates the count of distinct items added to it, what len(set.Reduce()) counts exactly, in 2^precision bytes instead of memory for every item. The standard error of Estimate is 1.04 / sqrt(2^precision), about 1.6% at the default precision 12, and estimates within three standard errors are expected. Estimators of the same precision can be merged, like after counting shards of a stream separately.
+//
+// Registers are chosen by the item's digest. The zero value can't be used; an estimator is created by NewHyperLogLog or UnmarshalBinary.
+type HyperLogLog struct {
+	registers []uint8
+	precision uint8
+}
+
+const (
+	MinHyperLogLogPrecision     = 4
+	MaxHyperLogLogPrecision     = 18
+	DefaultHyperLogLogPrecision = 12
+)
+
+const (
+	hllMagic   = "uhll"
+	hllVersion = 1
+)
+
+// Creates an empty estimator with 2^precision registers.
+func NewHyperLogLog(precision uint8) *HyperLogLog {
+	if asserting {
+		if (precision < MinHyperLogLogPrecision) || (precision > MaxHyperLogLogPrecision) {
+			panic(fmt.Sprintf("unordered: HyperLogLog precision %v not between %v and %v", precision, MinHyperLogLogPrecision, MaxHyperLogLogPrecision))
+		}
+	}
+	return &HyperLogLog{
+		registers: make([]uint8, 1<<precision),
+		precision: precision,
+	}
+}
+
+// Adds the item. An error is returned if the item is neither Hashable nor an encoding.BinaryMarshaler.
+func (a *HyperLogLog) Add(the Comparable) error {
+	if asserting {
+		if len(a.registers) == 0 {
+			panic("unordered: HyperLogLog not created with NewHyperLogLog")
+		}
+	}
+	d, err := digest(the)
+	if err != nil {
+		return err
+	}
+	// the first precision bits choose the register, which keeps the longest run of leading zeros seen in the rest
+	i := d >> (64 - a.precision)
+	rho := uint8(bits.LeadingZeros64(d<<a.precision|1<<(a.precision-1))) + 1
+	if rho > a.registers[i] {
+		a.registers[i] = rho
+	}
+	return nil
+}
+
+// Adds the items of the set.
+func (a *HyperLogLog) AddSet(the EqualSet) error {
+	for _, item := range the {
+		err := a.Add(item)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Adds items received from the channel until it's closed or the context is done, when ctx.Err is returned.
+func (a *HyperLogLog) AddChan(ctx context.Context, from <-chan Comparable) error {
+	if asserting {
+		if ctx == nil {
+			panic("unordered: nil context")
+		}
+		if from == nil {
+			panic("unordered: nil channel")
+		}
+	}
+	for {
+		select {
+		case item, ok := <-from:
+			if ok == false {
+				return nil
+			}
+			err := a.Add(item)
+			if err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Adds the items yielded by the iterator, which can be an iter.Seq[Comparable]. Iteration is stopped at the first error.
+func (a *HyperLogLog) AddSeq(seq func(yield func(Comparable) bool)) error {
+	var err error
+	seq(func(item Comparable) bool {
+		err = a.Add(item)
+		return err == nil
+	})
+	return err
+}
+
+// Returns the estimated count of distinct items added.
+func (a *HyperLogLog) Estimate() uint64 {
+	if asserting {
+		if len(a.registers) == 0 {
+			panic("unordered: HyperLogLog not created with NewHyperLogLog")
+		}
+	}
+	m := float64(len(a.registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range a.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	var alpha float64
+	switch len(a.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+	e := alpha * m * m / sum
+	// small counts are estimated by linear counting of the empty registers
+	if (e <= 2.5*m) && (zeros > 0) {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(math.Round(e))
+}
+
+// Returns the standard error of Estimate relative to the count, 1.04 / sqrt(2^precision).
+func (a *HyperLogLog) StandardError() float64 {
+	return 1.04 / math.Sqrt(float64(len(a.registers)))
+}
+
+// Adds the items of the other estimator to this one. The estimators must have the same precision, otherwise ErrIncompatible is returned.
+func (a *HyperLogLog) Merge(with *HyperLogLog) error {
+	if a.precision != with.precision {
+		return ErrIncompatible
+	}
+	for i, r := range with.registers {
+		if r > a.registers[i] {
+			a.registers[i] = r
+		}
+	}
+	return nil
+}
+
+// Encodes the estimator in a versioned binary layout:
+//     magic       4 bytes "uhll"
+//     version     1 byte, currently 1
+//     precision   1 byte
+//     registers   2^precision bytes
+//     checksum    4 bytes big-endian CRC-32 (Castagnoli) of all previous bytes
+func (a *HyperLogLog) MarshalBinary() ([]byte, error) {
+	out := make([]byte, 0, len(hllMagic)+2+len(a.registers)+4)
+	out = append(out, hllMagic...)
+	out = append(out, hllVersion, a.precision)
+	out = append(out, a.registers...)
+	return sealed(out), nil
+}
+
+// Decodes an estimator encoded by MarshalBinary. ErrChecksum is returned if the data was corrupted.
+func (a *HyperLogLog) UnmarshalBinary(data []byte) error {
+	body, err := unsealed(data, hllMagic, hllVersion)
+	if err != nil {
+		return err
+	}
+	if (len(body) < 1) || (body[0] < MinHyperLogLogPrecision) || (body[0] > MaxHyperLogLogPrecision) || (len(body)-1 != 1<<body[0]) {
+		return ErrFormat
+	}
+	registers := make([]uint8, len(body)-1)
+	copy(registers, body[1:])
+	*a = HyperLogLog{registers: registers, precision: body[0]}
+	return nil
+}
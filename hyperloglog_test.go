@@ -0,0 +1,128 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestHyperLogLogError(t *testing.T) {
+	for _, p := range []uint8{MinHyperLogLogPrecision, 10, DefaultHyperLogLogPrecision, 14} {
+		for _, n := range []int{10, 1000, 100000} {
+			h := NewHyperLogLog(p)
+			for i := 0; i < n; i++ {
+				// every item is added twice to check that duplicates aren't counted
+				for j := 0; j < 2; j++ {
+					err := h.Add(Int(i))
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			e := h.Estimate()
+			relative := math.Abs(float64(e)-float64(n)) / float64(n)
+			t.Logf("precision %2v count %6v: estimate %6v, error %.4f of standard %.4f", p, n, e, relative, h.StandardError())
+			if relative > 3*h.StandardError() {
+				t.Fatalf("precision %v count %v: estimate %v outside three standard errors", p, n, e)
+			}
+		}
+	}
+}
+
+func TestHyperLogLogSources(t *testing.T) {
+	set := make(EqualSet, 0, 3000)
+	for i := 0; i < 3000; i++ {
+		set = append(set, Coordinate{i % 1000, 0})
+	}
+	fromSet := NewHyperLogLog(DefaultHyperLogLogPrecision)
+	err := fromSet.AddSet(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromChan := NewHyperLogLog(DefaultHyperLogLogPrecision)
+	err = fromChan.AddChan(context.Background(), ToChan(context.Background(), set))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromSeq := NewHyperLogLog(DefaultHyperLogLogPrecision)
+	err = fromSeq.AddSeq(func(yield func(Comparable) bool) {
+		for _, item := range set {
+			if yield(item) == false {
+				return
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (fromSet.Estimate() != fromChan.Estimate()) || (fromSet.Estimate() != fromSeq.Estimate()) {
+		t.Fatal(fromSet.Estimate(), fromChan.Estimate(), fromSeq.Estimate())
+	}
+	if fromSeq.AddSeq(func(yield func(Comparable) bool) { yield(String("a")) }) == nil {
+		t.Fatal("added an item without a hash or binary encoding")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if fromChan.AddChan(ctx, make(chan Comparable)) != context.Canceled {
+		t.Fatal("canceled context not returned")
+	}
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	shards := make([]*HyperLogLog, 4)
+	whole := NewHyperLogLog(DefaultHyperLogLogPrecision)
+	for i := range shards {
+		shards[i] = NewHyperLogLog(DefaultHyperLogLogPrecision)
+	}
+	// shards overlap by half
+	for i := 0; i < 50000; i++ {
+		shards[i%4].Add(Int(i))
+		shards[(i+1)%4].Add(Int(i))
+		whole.Add(Int(i))
+	}
+	merged := NewHyperLogLog(DefaultHyperLogLogPrecision)
+	for _, s := range shards {
+		data, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded HyperLogLog
+		err = decoded.UnmarshalBinary(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = merged.Merge(&decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if merged.Estimate() != whole.Estimate() {
+		t.Fatal(merged.Estimate(), whole.Estimate())
+	}
+	if merged.Merge(NewHyperLogLog(10)) != ErrIncompatible {
+		t.Fatal("merged different precisions")
+	}
+	data, _ := merged.MarshalBinary()
+	data[6] ^= 1
+	if merged.UnmarshalBinary(data) != ErrChecksum {
+		t.Fatal("corrupt estimator decoded")
+	}
+}
+
+func TestHyperLogLogZeroValue(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("zero value estimator used")
+		}
+	}()
+	var zero HyperLogLog
+	zero.Add(Int(1))
+}
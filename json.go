@@ -0,0 +1,117 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// The JSON encoding of a set is an object with the registered type name and an array of the items encoded with encoding/json:
+//     {"type":"coord","items":[{"X":1,"Y":2},{"X":1,"Y":2}]}
+// Duplicates are kept so the item counts compared by EqualSet.Equal are preserved. An empty set has no type. The item type must be registered with RegisterType.
+type jsonSet struct {
+	Type  string            `json:"type,omitempty"`
+	Items []json.RawMessage `json:"items"`
+}
+
+// Encodes the set as JSON. A nil set is encoded as null.
+func (a Set) MarshalJSON() ([]byte, error) {
+	if a == nil {
+		return []byte("null"), nil
+	}
+	name, err := a.registeredName()
+	if err != nil {
+		return nil, err
+	}
+	out := jsonSet{
+		Type:  name,
+		Items: make([]json.RawMessage, len(a)),
+	}
+	for i, item := range a {
+		out.Items[i], err = json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("unordered: item %v: %w", i, err)
+		}
+	}
+	return json.Marshal(out)
+}
+
+// Decodes a set encoded by MarshalJSON, replacing the receiver's items. Unregistered type names and items that don't decode into the registered type are errors.
+func (a *Set) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var in jsonSet
+	err := json.Unmarshal(data, &in)
+	if err != nil {
+		return err
+	}
+	if in.Items == nil {
+		return fmt.Errorf("unordered: JSON set has no items array")
+	}
+	out := make(Set, len(in.Items))
+	if len(out) == 0 {
+		*a = out
+		return nil
+	}
+	if in.Type == "" {
+		return fmt.Errorf("unordered: JSON set with %v items has no type", len(in.Items))
+	}
+	t, err := registeredType(in.Type)
+	if err != nil {
+		return err
+	}
+	for i, raw := range in.Items {
+		ptr, item := newItem(t)
+		err = unmarshalItem(raw, ptr)
+		if err != nil {
+			return fmt.Errorf("unordered: item %v is not a %q (%v): %w", i, in.Type, t, err)
+		}
+		out[i] = item()
+	}
+	*a = out
+	return nil
+}
+
+// Encodes the set as JSON. A nil set is encoded as null.
+func (an EqualSet) MarshalJSON() ([]byte, error) {
+	if an == nil {
+		return []byte("null"), nil
+	}
+	return an.set().MarshalJSON()
+}
+
+// Decodes a set encoded by MarshalJSON, replacing the receiver's items. The registered type must be Comparable.
+func (an *EqualSet) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s Set
+	err := s.UnmarshalJSON(data)
+	if err != nil {
+		return err
+	}
+	if len(s) > 0 {
+		err = comparableType(s.typeof())
+		if err != nil {
+			return err
+		}
+	}
+	*an = s.equalset()
+	return nil
+}
+
+// Decodes one JSON item into the pointer. Object fields that the item type doesn't have are errors, so input of a different type isn't decoded into zero values.
+func unmarshalItem(data []byte, into interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	return d.Decode(into)
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func init() {
+	RegisterType("coord", Coordinate{})
+	RegisterType("int", Int(0))
+	RegisterType("string", String(""))
+}
+
+var JSONCases = []EqualSet{
+	{Coordinate{1, 2}, Coordinate{3, 4}, Coordinate{3, 4}},
+	{Int(1), Int(1), Int(1)},
+	{String("hello"), String("world")},
+	{},
+}
+
+func TestEqualSetJSON(t *testing.T) {
+	for i, c := range JSONCases {
+		data, err := json.Marshal(c)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		var out EqualSet
+		err = json.Unmarshal(data, &out)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		if out.Equal(c) == false {
+			t.Fatalf("%v: %v not equal to %v", i, out, c)
+		}
+	}
+}
+
+func TestSetJSON(t *testing.T) {
+	data, err := json.Marshal(Set{Coordinate{1, 2}, Coordinate{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"type":"coord","items":[{"X":1,"Y":2},{"X":1,"Y":2}]}` {
+		t.Fatalf("unexpected encoding %v", string(data))
+	}
+	var out Set
+	err = json.Unmarshal(data, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (len(out) != 2) || (out[0].(Coordinate) != Coordinate{1, 2}) {
+		t.Fatalf("unexpected set %v", out)
+	}
+}
+
+type JSONErrorCase struct {
+	JSON  string
+	Error string
+}
+
+var JSONErrorCases = []JSONErrorCase{
+	{
+		JSON:  `{"type":"point","items":[{"X":1,"Y":2}]}`,
+		Error: `"point" not registered`,
+	},
+	{
+		JSON:  `{"type":"coord","items":[{"X":1,"Y":2},"hello"]}`,
+		Error: `item 1 is not a "coord"`,
+	},
+	{
+		JSON:  `{"items":[1]}`,
+		Error: "has no type",
+	},
+	{
+		JSON:  `{"type":"coord"}`,
+		Error: "no items",
+	},
+	{
+		JSON:  `{"type":"coord","items":[{"Z":1}]}`,
+		Error: `item 0 is not a "coord"`,
+	},
+}
+
+func TestJSONErrors(t *testing.T) {
+	for i, c := range JSONErrorCases {
+		var out EqualSet
+		err := json.Unmarshal([]byte(c.JSON), &out)
+		if (err == nil) || (strings.Contains(err.Error(), c.Error) == false) {
+			t.Fatalf("%v: unexpected error %v", i, err)
+		}
+	}
+	_, err := json.Marshal(Set{Coordinate{1, 2}, Int(1)})
+	if err == nil {
+		t.Fatal("mixed type set encoded")
+	}
+	_, err = json.Marshal(Set{1.5})
+	if err == nil {
+		t.Fatal("unregistered type set encoded")
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// A MinHash is a signature of the distinct items of a set. The fraction of positions where two signatures are equal estimates the Jaccard index of their sets, with a standard error of about 1 / sqrt(len(signature)). Signatures are compared in time proportional to their length instead of the size of the sets.
+//
+// Each position hashes the item's digest.
+type MinHash []uint64
+
+// Computes a signature of the given length for the set. Each position is the minimum over the items of a differently seeded hash. An error is returned if an item is neither Hashable nor an encoding.BinaryMarshaler.
+func NewMinHash(set EqualSet, length int) (MinHash, error) {
+	if asserting {
+		if length < 1 {
+			panic("unordered: MinHash length less than 1")
+		}
+	}
+	out := make(MinHash, length)
+	for i := range out {
+		out[i] = math.MaxUint64
+	}
+	for _, item := range set {
+		d, err := digest(item)
+		if err != nil {
+			return nil, err
+		}
+		for i := range out {
+			h := mix(d ^ minHashSeed(i))
+			if h < out[i] {
+				out[i] = h
+			}
+		}
+	}
+	return out, nil
+}
+
+func minHashSeed(i int) uint64 {
+	return mix(uint64(i) + 0x6a09e667f3bcc908)
+}
+
+// Returns the estimated Jaccard index of the sets of the two signatures, which must have the same length.
+func (a MinHash) Jaccard(with MinHash) float64 {
+	if asserting {
+		if len(a) != len(with) {
+			panic(fmt.Sprintf("unordered: MinHash lengths %v and %v differ", len(a), len(with)))
+		}
+	}
+	equal := 0
+	for i, h := range a {
+		if h == with[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(a))
+}
+
+// An LSHIndex finds the sets similar to a query set without comparing the query to every set. Each set's MinHash signature is split into bands of rows, and sets that have all the rows of any band equal to the query's are candidates. Sets with Jaccard index s become candidates with probability 1 - (1 - s^rows)^bands, a threshold near (1 / bands)^(1 / rows): most sets above it are candidates and most below aren't.
+type LSHIndex struct {
+	bands, rows int
+	buckets     []map[uint64][]int
+	sets        []EqualSet
+}
+
+// Creates an empty index that computes signatures of bands times rows length.
+func NewLSHIndex(bands, rows int) *LSHIndex {
+	if asserting {
+		if (bands < 1) || (rows < 1) {
+			panic("unordered: LSHIndex bands or rows less than 1")
+		}
+	}
+	buckets := make([]map[uint64][]int, bands)
+	for i := range buckets {
+		buckets[i] = make(map[uint64][]int)
+	}
+	return &LSHIndex{
+		bands:   bands,
+		rows:    rows,
+		buckets: buckets,
+	}
+}
+
+// Adds the set to the index and returns its ID, which counts up from 0 in the order sets are added.
+func (an *LSHIndex) Add(set EqualSet) (int, error) {
+	sig, err := NewMinHash(set, an.bands*an.rows)
+	if err != nil {
+		return 0, err
+	}
+	id := len(an.sets)
+	an.sets = append(an.sets, set)
+	for b, key := range an.keys(sig) {
+		an.buckets[b][key] = append(an.buckets[b][key], id)
+	}
+	return id, nil
+}
+
+// Returns the set with the ID.
+func (an *LSHIndex) Set(id int) EqualSet {
+	return an.sets[id]
+}
+
+// Returns the count of sets in the index.
+func (an *LSHIndex) Len() int {
+	return len(an.sets)
+}
+
+// Returns the IDs, in increasing order, of the sets that share a band with the query set. Candidates include false positives that aren't similar, and may miss similar sets with the probability described for LSHIndex.
+func (an *LSHIndex) Candidates(query EqualSet) ([]int, error) {
+	sig, err := NewMinHash(query, an.bands*an.rows)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[int]struct{})
+	for b, key := range an.keys(sig) {
+		for _, id := range an.buckets[b][key] {
+			found[id] = struct{}{}
+		}
+	}
+	out := make([]int, 0, len(found))
+	for id := range found {
+		out = append(out, id)
+	}
+	sort.Ints(out)
+	return out, nil
+}
+
+// Returns the IDs, in increasing order, of the candidates whose exact Jaccard index with the query set is at least the threshold.
+func (an *LSHIndex) Similar(query EqualSet, threshold float64) ([]int, error) {
+	candidates, err := an.Candidates(query)
+	if err != nil {
+		return nil, err
+	}
+	out := candidates[:0]
+	for _, id := range candidates {
+		if Jaccard(query, an.sets[id]) >= threshold {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+// Hashes the rows of each band of the signature into one key.
+func (an *LSHIndex) keys(sig MinHash) []uint64 {
+	out := make([]uint64, an.bands)
+	for b := range out {
+		key := uint64(b)
+		for _, h := range sig[b*an.rows : (b+1)*an.rows] {
+			key = mix(key ^ h)
+		}
+		out[b] = key
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Returns two sets of distinct items sharing the count of items in both, each with the count of items only in it.
+func overlapping(r *rand.Rand, both, onlyA, onlyB int) (EqualSet, EqualSet) {
+	a := make(EqualSet, 0, both+onlyA)
+	b := make(EqualSet, 0, both+onlyB)
+	next := func() Int { return Int(r.Int63()) }
+	for i := 0; i < both; i++ {
+		item := next()
+		a = append(a, item)
+		b = append(b, item)
+	}
+	for i := 0; i < onlyA; i++ {
+		a = append(a, next())
+	}
+	for i := 0; i < onlyB; i++ {
+		b = append(b, next())
+	}
+	return a, b
+}
+
+func TestMinHashJaccard(t *testing.T) {
+	const length = 256
+	r := rand.New(rand.NewSource(6))
+	for _, c := range []struct{ both, onlyA, onlyB int }{
+		{1000, 0, 0},
+		{800, 100, 100},
+		{500, 250, 250},
+		{100, 500, 400},
+		{0, 300, 300},
+	} {
+		a, b := overlapping(r, c.both, c.onlyA, c.onlyB)
+		sa, err := NewMinHash(a, length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sb, err := NewMinHash(b, length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exact := Jaccard(a, b)
+		estimate := sa.Jaccard(sb)
+		t.Logf("exact %.3f estimate %.3f", exact, estimate)
+		if math.Abs(exact-estimate) > 3/math.Sqrt(length) {
+			t.Fatalf("estimate %v of %v", estimate, exact)
+		}
+	}
+	sc, err := NewMinHash(EqualSet{Coordinate{1, 2}, Coordinate{1, 2}}, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd, _ := NewMinHash(EqualSet{Coordinate{1, 2}}, 8)
+	if sc.Jaccard(sd) != 1 {
+		t.Fatal("duplicates changed the signature")
+	}
+	_, err = NewMinHash(EqualSet{String("a")}, 8)
+	if err == nil {
+		t.Fatal("hashed an item without a hash or binary encoding")
+	}
+}
+
+func TestLSHIndex(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	index := NewLSHIndex(20, 5)
+	queries := make([]EqualSet, 0, 10)
+	near := make([]int, 0, 10)
+	for i := 0; i < 300; i++ {
+		if i%30 != 0 {
+			a, _ := overlapping(r, 0, 200, 0)
+			_, err := index.Add(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		// Jaccard index 180 / 220
+		a, b := overlapping(r, 180, 20, 20)
+		id, err := index.Add(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		queries = append(queries, b)
+		near = append(near, id)
+	}
+	for i, q := range queries {
+		candidates, err := index.Candidates(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(candidates) > 5 {
+			t.Fatalf("%v candidates", len(candidates))
+		}
+		similar, err := index.Similar(q, 0.8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (len(similar) != 1) || (similar[0] != near[i]) {
+			t.Fatalf("query %v found %v, expected %v", i, similar, near[i])
+		}
+		if Jaccard(q, index.Set(similar[0])) != 180.0/220 {
+			t.Fatal(Jaccard(q, index.Set(similar[0])))
+		}
+	}
+	if index.Len() != 300 {
+		t.Fatal(index.Len())
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// A ChangeKind describes what a Change did to an ObservableSet.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota + 1
+	Removed
+)
+
+func (a ChangeKind) String() string {
+	switch a {
+	case Added:
+		return "Added"
+	case Removed:
+		return "Removed"
+	}
+	return "ChangeKind(invalid)"
+}
+
+// A Change is sent to subscribers of an ObservableSet after each mutation that modified the set. Count is the number of copies of Item that were added or removed.
+type Change struct {
+	Kind  ChangeKind
+	Item  Comparable
+	Count int
+}
+
+// The OverflowPolicy of a channel subscription decides what happens when a change is sent while the subscriber's channel buffer is full.
+type OverflowPolicy int
+
+const (
+	// The mutating call waits until the subscriber receives the change. One slow subscriber slows every writer of the set.
+	Block OverflowPolicy = iota
+	// The change is discarded and counted by Subscription.Dropped. The subscriber should resync from ObservableSet.Set when the count increases.
+	Drop
+	// The subscription is canceled and its channel closed. The subscriber should resync from ObservableSet.Set and subscribe again.
+	Close
+)
+
+// An ObservableSet is an EqualSet that notifies subscribers of every change made with Add, Remove, and RemoveAll. Unlike EqualSet the methods modify the receiver, and are safe to call from multiple goroutines. Begin starts a Transaction that applies many changes at once.
+//
+// Subscribers receive changes in the order the mutations happened. Callbacks are called synchronously by the mutating goroutine and may read the set with Set, Has, or Len, but must not modify it.
+//
+// The zero value can't be used; a set is created by NewObservableSet.
+type ObservableSet struct {
+	mutex   sync.Mutex
+	notify  sync.Mutex
+	set     EqualSet
+	subs    map[*Subscription]struct{}
+	version uint64
+	log     []modification
+	checked map[*Transaction]struct{}
+}
+
+// A Subscription is the registration of a callback or channel with an ObservableSet.
+type Subscription struct {
+	dropped  uint64 // first for 64-bit alignment of atomic operations
+	of       *ObservableSet
+	callback func(Change)
+	channel  chan Change
+	policy   OverflowPolicy
+	done     chan struct{}
+	once     sync.Once
+	closed   bool
+}
+
+// Creates an ObservableSet holding a copy of the items in the argument set.
+func NewObservableSet(from EqualSet) *ObservableSet {
+	if asserting {
+		if from == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	out := make(EqualSet, len(from))
+	copy(out, from)
+	return &ObservableSet{
+		set:     out,
+		subs:    make(map[*Subscription]struct{}),
+		checked: make(map[*Transaction]struct{}),
+	}
+}
+
+// Registers a callback that is called with each change.
+func (an *ObservableSet) Subscribe(callback func(Change)) *Subscription {
+	if asserting {
+		if callback == nil {
+			panic("unordered: nil callback")
+		}
+	}
+	s := &Subscription{
+		of:       an,
+		callback: callback,
+		done:     make(chan struct{}),
+	}
+	an.mutex.Lock()
+	an.subs[s] = struct{}{}
+	an.mutex.Unlock()
+	return s
+}
+
+// Registers a channel with the buffer size that receives each change. The policy decides what happens when the buffer is full. The channel is closed when the subscription is canceled.
+func (an *ObservableSet) SubscribeChan(buffer int, policy OverflowPolicy) (<-chan Change, *Subscription) {
+	if asserting {
+		if buffer < 0 {
+			panic("unordered: negative buffer size")
+		}
+		if (policy < Block) || (policy > Close) {
+			panic("unordered: invalid overflow policy")
+		}
+	}
+	s := &Subscription{
+		of:      an,
+		channel: make(chan Change, buffer),
+		policy:  policy,
+		done:    make(chan struct{}),
+	}
+	an.mutex.Lock()
+	an.subs[s] = struct{}{}
+	an.mutex.Unlock()
+	return s.channel, s
+}
+
+// Adds an item to the set and sends an Added change.
+func (an *ObservableSet) Add(the Comparable) {
+	an.run([]setOp{{opAdd, the}}, nil)
+}
+
+// Removes one matching item. A Removed change is sent if an item was removed.
+func (an *ObservableSet) Remove(the Comparable) {
+	an.run([]setOp{{opRemove, the}}, nil)
+}
+
+// Removes all matching items. A Removed change with the count of removed items is sent if any were removed.
+func (an *ObservableSet) RemoveAll(the Comparable) {
+	an.run([]setOp{{opRemoveAll, the}}, nil)
+}
+
+// A setOp is an Add, Remove, or RemoveAll of an item.
+type setOp struct {
+	kind setOpKind
+	item Comparable
+}
+
+type setOpKind int
+
+const (
+	opAdd setOpKind = iota + 1
+	opRemove
+	opRemoveAll
+)
+
+// Applies the operations together and then sends their changes. If the check function returns an error then nothing is applied.
+func (an *ObservableSet) run(ops []setOp, check func() error) error {
+	an.notify.Lock()
+	defer an.notify.Unlock()
+	changes, subs, err := an.applyAll(ops, check)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		an.send(subs, c)
+	}
+	return nil
+}
+
+// Applies the operations with the mutex held and returns their changes and the subscribers to send them to.
+func (an *ObservableSet) applyAll(ops []setOp, check func() error) ([]Change, []*Subscription, error) {
+	an.mutex.Lock()
+	defer an.mutex.Unlock()
+	if check != nil {
+		err := check()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	changes := make([]Change, 0, len(ops))
+	for _, op := range ops {
+		c, changed := an.apply(op)
+		if changed {
+			changes = append(changes, c)
+		}
+	}
+	return changes, an.subscribers(), nil
+}
+
+// Must be called with the mutex held.
+func (an *ObservableSet) apply(op setOp) (Change, bool) {
+	l := len(an.set)
+	switch op.kind {
+	case opAdd:
+		an.set = an.set.Add(op.item)
+	case opRemove:
+		an.set = an.set.Remove(op.item)
+	case opRemoveAll:
+		an.set = an.set.RemoveAll(op.item)
+	}
+	if len(an.set) == l {
+		return Change{}, false
+	}
+	an.modified(op.item)
+	if op.kind == opAdd {
+		return Change{Kind: Added, Item: op.item, Count: 1}, true
+	}
+	return Change{Kind: Removed, Item: op.item, Count: l - len(an.set)}, true
+}
+
+// Returns a copy of the current items as an EqualSet.
+func (an *ObservableSet) Set() EqualSet {
+	an.mutex.Lock()
+	defer an.mutex.Unlock()
+	out := make(EqualSet, len(an.set))
+	copy(out, an.set)
+	return out
+}
+
+// If the set has the item then true is returned.
+func (an *ObservableSet) Has(the Comparable) bool {
+	an.mutex.Lock()
+	defer an.mutex.Unlock()
+	return an.set.Has(the)
+}
+
+// Returns the count of items in the set.
+func (an *ObservableSet) Len() int {
+	an.mutex.Lock()
+	defer an.mutex.Unlock()
+	return len(an.set)
+}
+
+// Must be called with the mutex held.
+func (an *ObservableSet) subscribers() []*Subscription {
+	out := make([]*Subscription, 0, len(an.subs))
+	for s := range an.subs {
+		out = append(out, s)
+	}
+	return out
+}
+
+// Must be called with the notify mutex held.
+func (an *ObservableSet) send(to []*Subscription, the Change) {
+	for _, s := range to {
+		if s.callback != nil {
+			select {
+			case <-s.done:
+			default:
+				s.callback(the)
+			}
+			continue
+		}
+		if s.closed {
+			continue
+		}
+		switch s.policy {
+		case Block:
+			select {
+			case s.channel <- the:
+			case <-s.done:
+			}
+		case Drop:
+			select {
+			case s.channel <- the:
+			default:
+				atomic.AddUint64(&s.dropped, 1)
+			}
+		case Close:
+			select {
+			case s.channel <- the:
+			default:
+				s.cancel()
+				s.closed = true
+				close(s.channel)
+			}
+		}
+	}
+}
+
+// Removes the subscription from the set. No changes are sent after Cancel returns, so Cancel waits for a callback or channel send in progress, and a subscription channel is closed. Cancel must not be called from a callback of the same set.
+func (a *Subscription) Cancel() {
+	a.cancel()
+	a.of.notify.Lock()
+	if (a.channel != nil) && (a.closed == false) {
+		a.closed = true
+		close(a.channel)
+	}
+	a.of.notify.Unlock()
+}
+
+func (a *Subscription) cancel() {
+	a.once.Do(func() {
+		close(a.done)
+		a.of.mutex.Lock()
+		delete(a.of.subs, a)
+		a.of.mutex.Unlock()
+	})
+}
+
+// Returns the count of changes discarded because of the Drop overflow policy.
+func (a *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
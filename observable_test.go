@@ -0,0 +1,135 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObservableSetCallback(t *testing.T) {
+	set := NewObservableSet(EqualSet{Int(1)})
+	changes := make([]Change, 0, 4)
+	s := set.Subscribe(func(c Change) {
+		changes = append(changes, c)
+	})
+	set.Add(Int(2))
+	set.Add(Int(2))
+	set.Remove(Int(3))
+	set.RemoveAll(Int(2))
+	set.Remove(Int(1))
+	expected := []Change{
+		{Kind: Added, Item: Int(2), Count: 1},
+		{Kind: Added, Item: Int(2), Count: 1},
+		{Kind: Removed, Item: Int(2), Count: 2},
+		{Kind: Removed, Item: Int(1), Count: 1},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("%v changes, expected %v", len(changes), len(expected))
+	}
+	for i, c := range expected {
+		if changes[i] != c {
+			t.Fatalf("%v: %v, expected %v", i, changes[i], c)
+		}
+	}
+	s.Cancel()
+	set.Add(Int(4))
+	if len(changes) != len(expected) {
+		t.Fatal("change sent after Cancel")
+	}
+	if set.Set().Equal(EqualSet{Int(4)}) == false {
+		t.Fatal("set not equal")
+	}
+}
+
+func TestObservableSetCancelWaits(t *testing.T) {
+	set := NewObservableSet(EqualSet{})
+	started, release := make(chan struct{}), make(chan struct{})
+	s := set.Subscribe(func(c Change) {
+		close(started)
+		<-release
+	})
+	go set.Add(Int(1))
+	<-started
+	canceled := make(chan struct{})
+	go func() {
+		s.Cancel()
+		close(canceled)
+	}()
+	select {
+	case <-canceled:
+		t.Fatal("Cancel returned during a callback")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-canceled
+}
+
+func TestObservableSetChan(t *testing.T) {
+	set := NewObservableSet(EqualSet{})
+	changes, s := set.SubscribeChan(4, Block)
+	done := make(chan EqualSet)
+	go func() {
+		mirror := EqualSet{}
+		for c := range changes {
+			for i := 0; i < c.Count; i++ {
+				if c.Kind == Added {
+					mirror = mirror.Add(c.Item)
+				} else {
+					mirror = mirror.Remove(c.Item)
+				}
+			}
+		}
+		done <- mirror
+	}()
+	for i := 0; i < 100; i++ {
+		set.Add(Int(i % 10))
+	}
+	for i := 0; i < 10; i += 2 {
+		set.RemoveAll(Int(i))
+	}
+	s.Cancel()
+	if (<-done).Equal(set.Set()) == false {
+		t.Fatal("mirror not equal")
+	}
+}
+
+func TestObservableSetOverflow(t *testing.T) {
+	set := NewObservableSet(EqualSet{})
+	_, dropping := set.SubscribeChan(1, Drop)
+	closing, _ := set.SubscribeChan(1, Close)
+	set.Add(Int(1))
+	set.Add(Int(2))
+	set.Add(Int(3))
+	if dropping.Dropped() != 2 {
+		t.Fatalf("dropped %v, expected 2", dropping.Dropped())
+	}
+	if c := <-closing; c.Item != Int(1) {
+		t.Fatalf("first change %v", c)
+	}
+	if _, open := <-closing; open {
+		t.Fatal("overflowed channel not closed")
+	}
+	if set.Len() != 3 {
+		t.Fatal("set not length 3")
+	}
+}
+
+func TestObservableSetPanic(t *testing.T) {
+	set := NewObservableSet(EqualSet{Int(1)})
+	func() {
+		defer func() { recover() }()
+		set.Add(String("a"))
+	}()
+	// the panic must not leave the set locked
+	set.Add(Int(2))
+	if set.Len() != 2 {
+		t.Fatalf("unexpected set %v", set.Set())
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"sort"
+)
+
+// An Ordered is a Comparable that can be sorted. Less must be a strict weak ordering that agrees with Equal: two items are Equal exactly when neither is Less than the other. Like Equal, Less is only called with items of the same underlying type.
+type Ordered interface {
+	Comparable
+	Less(than Comparable) bool
+}
+
+// A counted item is one distinct item and how many copies of it a set has.
+type counted struct {
+	item  Ordered
+	count uint64
+}
+
+// Sorts a copy of the items of the set and groups equal items with their counts. Every item must be Ordered.
+func sortedCounts(the EqualSet) []counted {
+	items := make([]Ordered, len(the))
+	for i, item := range the {
+		items[i] = item.(Ordered)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Less(items[j])
+	})
+	out := make([]counted, 0, len(items))
+	for _, item := range items {
+		if (len(out) > 0) && out[len(out)-1].item.Equal(item) {
+			out[len(out)-1].count++
+			continue
+		}
+		out = append(out, counted{item: item, count: 1})
+	}
+	return out
+}
@@ -0,0 +1,39 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"testing"
+)
+
+// satisfies Ordered, sorting by X then Y
+func (the Coordinate) Less(than Comparable) bool {
+	c := than.(Coordinate)
+	if the.X != c.X {
+		return the.X < c.X
+	}
+	return the.Y < c.Y
+}
+
+func (i Int) Less(than Comparable) bool {
+	return i < than.(Int)
+}
+
+func TestSortedCounts(t *testing.T) {
+	out := sortedCounts(EqualSet{Int(3), Int(1), Int(3), Int(2), Int(3), Int(1)})
+	expected := []counted{{Int(1), 2}, {Int(2), 1}, {Int(3), 3}}
+	if len(out) != len(expected) {
+		t.Fatalf("unexpected counts %v", out)
+	}
+	for i, c := range expected {
+		if out[i] != c {
+			t.Fatalf("%v: %v, expected %v", i, out[i], c)
+		}
+	}
+}
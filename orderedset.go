@@ -0,0 +1,160 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+// An OrderedSet is an EqualSet that keeps its items in the order they were added, for output like lists and reports that should be the same every run. Equal still ignores order; SequenceEqual doesn't. Unlike EqualSet the Add, Remove, and MoveToFront methods modify the receiver. The zero value is an empty set.
+//
+// Has and Count take constant time for Hashable items. Remove, RemoveAll, Index, and MoveToFront take time proportional to the length of the set.
+type OrderedSet struct {
+	items  EqualSet
+	counts Bag
+}
+
+// Creates an OrderedSet with the items of the set in slice order.
+func NewOrderedSet(from EqualSet) *OrderedSet {
+	out := &OrderedSet{items: make(EqualSet, 0, len(from))}
+	for _, item := range from {
+		out.Add(item)
+	}
+	return out
+}
+
+// Adds the item at the end of the order. Duplicates are allowed.
+func (an *OrderedSet) Add(the Comparable) {
+	an.items = append(an.items, the)
+	an.counts.Add(the, 1)
+}
+
+// Removes the first matching item. If no item was removed then false is returned.
+func (an *OrderedSet) Remove(the Comparable) bool {
+	i := an.Index(the)
+	if i == -1 {
+		return false
+	}
+	copy(an.items[i:], an.items[i+1:])
+	an.items[len(an.items)-1] = nil
+	an.items = an.items[:len(an.items)-1]
+	an.counts.Remove(the, 1)
+	return true
+}
+
+// Removes all matching items and returns the count removed.
+func (an *OrderedSet) RemoveAll(the Comparable) int {
+	if an.counts.Has(the) == false {
+		return 0
+	}
+	out := an.items[:0]
+	for _, item := range an.items {
+		if item.Equal(the) == false {
+			out = append(out, item)
+		}
+	}
+	for i := len(out); i < len(an.items); i++ {
+		an.items[i] = nil
+	}
+	an.items = out
+	return an.counts.RemoveAll(the)
+}
+
+// If the set has the item then true is returned.
+func (an *OrderedSet) Has(the Comparable) bool {
+	return an.counts.Has(the)
+}
+
+// Returns the count of copies of the item in the set.
+func (an *OrderedSet) Count(the Comparable) int {
+	return an.counts.Count(the)
+}
+
+// Returns the count of items in the set.
+func (an *OrderedSet) Len() int {
+	return len(an.items)
+}
+
+// Returns the item at the index of the order.
+func (an *OrderedSet) At(index int) Comparable {
+	return an.items[index]
+}
+
+// Returns the index of the first matching item, or -1 if the set doesn't have it.
+func (an *OrderedSet) Index(the Comparable) int {
+	if an.counts.Has(the) == false {
+		return -1
+	}
+	for i, item := range an.items {
+		if item.Equal(the) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Moves the first matching item to the start of the order. If the set doesn't have the item then false is returned.
+func (an *OrderedSet) MoveToFront(the Comparable) bool {
+	i := an.Index(the)
+	if i == -1 {
+		return false
+	}
+	item := an.items[i]
+	copy(an.items[1:i+1], an.items[:i])
+	an.items[0] = item
+	return true
+}
+
+// Returns the items as an EqualSet in order.
+func (an *OrderedSet) EqualSet() EqualSet {
+	out := make(EqualSet, len(an.items))
+	copy(out, an.items)
+	return out
+}
+
+// Returns a set with the first of each group of equal items, in order.
+func (an *OrderedSet) Reduce() *OrderedSet {
+	out := &OrderedSet{items: make(EqualSet, 0, an.counts.Distinct())}
+	for _, item := range an.items {
+		if out.counts.Has(item) == false {
+			out.Add(item)
+		}
+	}
+	return out
+}
+
+// Provides a set of the items not in both sets: the receiver's items in order followed by the argument's items in order. Duplicates are not removed.
+func (an *OrderedSet) Diff(from *OrderedSet) *OrderedSet {
+	out := &OrderedSet{}
+	for _, item := range an.items {
+		if from.Has(item) == false {
+			out.Add(item)
+		}
+	}
+	for _, item := range from.items {
+		if an.Has(item) == false {
+			out.Add(item)
+		}
+	}
+	return out
+}
+
+// If both sets contain an equal count of each item then true is returned, in any order.
+func (an *OrderedSet) Equal(to *OrderedSet) bool {
+	return an.counts.Equal(&to.counts)
+}
+
+// If both sets have equal items in the same order then true is returned.
+func (an *OrderedSet) SequenceEqual(to *OrderedSet) bool {
+	if len(an.items) != len(to.items) {
+		return false
+	}
+	for i, item := range an.items {
+		if item.Equal(to.items[i]) == false {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"testing"
+)
+
+func sequence(the ...int) EqualSet {
+	out := make(EqualSet, len(the))
+	for i, n := range the {
+		out[i] = Coordinate{n, 0}
+	}
+	return out
+}
+
+func TestOrderedSet(t *testing.T) {
+	s := NewOrderedSet(sequence(3, 1, 2, 1, 4))
+	expect := func(msg string, the ...int) {
+		t.Helper()
+		if NewOrderedSet(sequence(the...)).SequenceEqual(s) == false {
+			t.Fatalf("%v: %v, expected %v", msg, s.EqualSet(), sequence(the...))
+		}
+	}
+	expect("NewOrderedSet", 3, 1, 2, 1, 4)
+	if (s.Len() != 5) || (s.Count(Coordinate{1, 0}) != 2) || (s.At(2).Equal(Coordinate{2, 0}) == false) || (s.Index(Coordinate{1, 0}) != 1) || (s.Index(Coordinate{9, 0}) != -1) {
+		t.Fatal("access")
+	}
+	s.Add(Coordinate{5, 0})
+	expect("Add", 3, 1, 2, 1, 4, 5)
+	if s.MoveToFront(Coordinate{4, 0}) == false {
+		t.Fatal("MoveToFront")
+	}
+	expect("MoveToFront", 4, 3, 1, 2, 1, 5)
+	if s.MoveToFront(Coordinate{9, 0}) {
+		t.Fatal("moved a missing item")
+	}
+	expect("MoveToFront of a missing item", 4, 3, 1, 2, 1, 5)
+	r := s.Reduce()
+	if NewOrderedSet(sequence(4, 3, 1, 2, 5)).SequenceEqual(r) == false {
+		t.Fatal("Reduce", r.EqualSet())
+	}
+	if (s.Remove(Coordinate{1, 0}) == false) || s.Remove(Coordinate{9, 0}) {
+		t.Fatal("Remove")
+	}
+	expect("Remove", 4, 3, 2, 1, 5)
+	s.Add(Coordinate{3, 0})
+	if s.RemoveAll(Coordinate{3, 0}) != 2 {
+		t.Fatal("RemoveAll")
+	}
+	expect("RemoveAll", 4, 2, 1, 5)
+	d := s.Diff(NewOrderedSet(sequence(7, 1, 6, 4)))
+	if NewOrderedSet(sequence(2, 5, 7, 6)).SequenceEqual(d) == false {
+		t.Fatal("Diff", d.EqualSet())
+	}
+	if r.Has(Coordinate{3, 0}) == false {
+		t.Fatal("Reduce result shares state")
+	}
+}
+
+func TestOrderedSetEqual(t *testing.T) {
+	a := NewOrderedSet(EqualSet{Int(1), Int(2), Int(2)})
+	b := NewOrderedSet(EqualSet{Int(2), Int(1), Int(2)})
+	if (a.Equal(b) == false) || a.SequenceEqual(b) {
+		t.Fatal("order affected Equal or not SequenceEqual")
+	}
+	b.RemoveAll(Int(1))
+	b.Add(Int(1))
+	if a.Equal(b) == false {
+		t.Fatal("Equal after modification")
+	}
+	b.Add(Int(2))
+	if a.Equal(b) || (a.EqualSet().Equal(b.EqualSet().Remove(Int(2))) == false) {
+		t.Fatal("counts ignored")
+	}
+	var empty OrderedSet
+	if (empty.Has(Int(1))) || (empty.Equal(NewOrderedSet(EqualSet{})) == false) {
+		t.Fatal("zero value")
+	}
+}
@@ -0,0 +1,358 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// A Patch is the changes that turn one set into another, with at most one Change for each distinct item. An Added change is the count of copies of the item to add and a Removed change the count to remove. Unlike EqualSet.Diff a Patch has a direction and counts, so it brings a set up to date without shipping the whole set.
+type Patch []Change
+
+// ErrNotPresent is returned by Apply for a patch that removes more copies of an item than the set has.
+var ErrNotPresent = errors.New("unordered: patch removes items the set doesn't have")
+
+// Returns the patch that turns the first set into the second.
+func Delta(from, to EqualSet) Patch {
+	if asserting {
+		if from == nil {
+			panic("unordered: nil set")
+		}
+		if to == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	out := make(Patch, 0)
+	for _, c := range tally(from, to) {
+		diff := c.b - c.a
+		if diff > 0 {
+			out = append(out, Change{Kind: Added, Item: c.item, Count: diff})
+		} else if diff < 0 {
+			out = append(out, Change{Kind: Removed, Item: c.item, Count: -diff})
+		}
+	}
+	return out
+}
+
+// Returns a new set with the patch applied. The set isn't changed. ErrNotPresent is returned if the patch removes more copies of an item than the set has, and an error is returned for a change that isn't an Added or Removed of at least one copy.
+func Apply(to EqualSet, the Patch) (EqualSet, error) {
+	if asserting {
+		if to == nil {
+			panic("unordered: nil set")
+		}
+	}
+	// the count of each item is followed through the changes in order, so repeated changes to an item are checked by their net effect
+	type running struct {
+		item  Comparable
+		count int
+	}
+	counts := make([]running, 0, len(the))
+	for i, c := range the {
+		if ((c.Kind != Added) && (c.Kind != Removed)) || (c.Count < 1) {
+			return nil, fmt.Errorf("unordered: change %v has kind %v and count %v", i, c.Kind, c.Count)
+		}
+	}
+	for _, c := range the {
+		i := 0
+		for ; i < len(counts); i++ {
+			if counts[i].item.Equal(c.Item) {
+				break
+			}
+		}
+		if i == len(counts) {
+			counts = append(counts, running{c.Item, countOf(to, c.Item)})
+		}
+		counts[i].count += c.signed()
+		if counts[i].count < 0 {
+			return nil, fmt.Errorf("%w: %v copies of %v", ErrNotPresent, c.Count, c.Item)
+		}
+	}
+	return the.apply(append(make(EqualSet, 0, len(to)), to...)), nil
+}
+
+// Returns an error if the patch has more than one change for an item.
+func (a Patch) distinct() error {
+	seen := newIndex()
+	for _, c := range a {
+		if seen.has(c.Item) {
+			return fmt.Errorf("unordered: patch has more than one change for %v", c.Item)
+		}
+		seen.add(c.Item)
+	}
+	return nil
+}
+
+// Returns the patch that undoes this patch.
+func (a Patch) Invert() Patch {
+	out := make(Patch, len(a))
+	for i, c := range a {
+		out[i] = c
+		if c.Kind == Added {
+			out[i].Kind = Removed
+		} else {
+			out[i].Kind = Added
+		}
+	}
+	return out
+}
+
+// Returns one patch with the effect of applying this patch and then the argument patch. Changes to the same item are combined into their net change.
+func (a Patch) Compose(then Patch) Patch {
+	out := make(Patch, 0, len(a)+len(then))
+	for _, c := range append(append(Patch{}, a...), then...) {
+		n := c.signed()
+		found := false
+		for i := range out {
+			if out[i].Item.Equal(c.Item) {
+				out[i] = signedChange(out[i].Item, out[i].signed()+n)
+				found = true
+				break
+			}
+		}
+		if found == false {
+			out = append(out, c)
+		}
+	}
+	// changes that cancel out are removed
+	net := out[:0]
+	for _, c := range out {
+		if c.Count != 0 {
+			net = append(net, c)
+		}
+	}
+	return net
+}
+
+func (a Change) signed() int {
+	if a.Kind == Removed {
+		return -a.Count
+	}
+	return a.Count
+}
+
+func signedChange(item Comparable, n int) Change {
+	if n < 0 {
+		return Change{Kind: Removed, Item: item, Count: -n}
+	}
+	return Change{Kind: Added, Item: item, Count: n}
+}
+
+// Applies the patch to the set, reusing its backing array. Removals of missing items are ignored.
+func (a Patch) apply(to EqualSet) EqualSet {
+	for _, c := range a {
+		if c.Kind == Added {
+			for i := 0; i < c.Count; i++ {
+				to = append(to, c.Item)
+			}
+			continue
+		}
+		for i := 0; i < c.Count; i++ {
+			to, _ = removeOne(to, c.Item)
+		}
+	}
+	return to
+}
+
+func countOf(in EqualSet, the Comparable) int {
+	n := 0
+	for _, item := range in {
+		if item.Equal(the) {
+			n++
+		}
+	}
+	return n
+}
+
+// Returns the changed items in patch order.
+func (a Patch) items() Set {
+	out := make(Set, len(a))
+	for i, c := range a {
+		out[i] = c.Item
+	}
+	return out
+}
+
+// The JSON encoding of a patch is the registered type name, like the JSON encoding of a set, and the changes:
+//     {"type":"coord","changes":[{"op":"add","item":{"X":1,"Y":2},"count":2},{"op":"remove","item":{"X":3,"Y":4},"count":1}]}
+type jsonPatch struct {
+	Type    string       `json:"type,omitempty"`
+	Changes []jsonChange `json:"changes"`
+}
+
+type jsonChange struct {
+	Op    string          `json:"op"`
+	Item  json.RawMessage `json:"item"`
+	Count int             `json:"count"`
+}
+
+// Encodes the patch as JSON. The item type must be registered with RegisterType.
+func (a Patch) MarshalJSON() ([]byte, error) {
+	name, err := a.items().registeredName()
+	if err != nil {
+		return nil, err
+	}
+	out := jsonPatch{
+		Type:    name,
+		Changes: make([]jsonChange, len(a)),
+	}
+	for i, c := range a {
+		out.Changes[i].Op = "add"
+		if c.Kind == Removed {
+			out.Changes[i].Op = "remove"
+		}
+		out.Changes[i].Count = c.Count
+		out.Changes[i].Item, err = json.Marshal(c.Item)
+		if err != nil {
+			return nil, fmt.Errorf("unordered: change %v: %w", i, err)
+		}
+	}
+	return json.Marshal(out)
+}
+
+// Decodes a patch encoded by MarshalJSON.
+func (a *Patch) UnmarshalJSON(data []byte) error {
+	var in jsonPatch
+	err := json.Unmarshal(data, &in)
+	if err != nil {
+		return err
+	}
+	out := make(Patch, len(in.Changes))
+	if len(out) == 0 {
+		*a = out
+		return nil
+	}
+	t, err := registeredType(in.Type)
+	if err != nil {
+		return err
+	}
+	err = comparableType(t)
+	if err != nil {
+		return err
+	}
+	for i, c := range in.Changes {
+		switch c.Op {
+		case "add":
+			out[i].Kind = Added
+		case "remove":
+			out[i].Kind = Removed
+		default:
+			return fmt.Errorf("unordered: change %v has unknown op %q", i, c.Op)
+		}
+		if c.Count < 1 {
+			return fmt.Errorf("unordered: change %v has count %v", i, c.Count)
+		}
+		out[i].Count = c.Count
+		ptr, item := newItem(t)
+		err = unmarshalItem(c.Item, ptr)
+		if err != nil {
+			return fmt.Errorf("unordered: change %v item is not a %q (%v): %w", i, in.Type, t, err)
+		}
+		out[i].Item = item().(Comparable)
+	}
+	err = out.distinct()
+	if err != nil {
+		return err
+	}
+	*a = out
+	return nil
+}
+
+const (
+	patchMagic   = "upat"
+	patchVersion = 1
+)
+
+// The op byte of a change in the binary layout.
+const (
+	patchAdd    = 1
+	patchRemove = 2
+)
+
+// Encodes the patch in a versioned binary layout:
+//     magic       4 bytes "upat"
+//     version     1 byte, currently 1
+//     count       uvarint count of changes
+//     changes     for each change 1 byte op, 1 for add or 2 for remove, and a uvarint count
+//     items       the binary encoding of a Set of the changed items in change order, see Set.MarshalBinary
+//     checksum    4 bytes big-endian CRC-32 (Castagnoli) of all previous bytes
+func (a Patch) MarshalBinary() ([]byte, error) {
+	items, err := a.items().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, 16+len(a)*3+len(items))
+	out = append(out, patchMagic...)
+	out = append(out, patchVersion)
+	out = binary.AppendUvarint(out, uint64(len(a)))
+	for _, c := range a {
+		op := byte(patchAdd)
+		if c.Kind == Removed {
+			op = patchRemove
+		}
+		out = append(out, op)
+		out = binary.AppendUvarint(out, uint64(c.Count))
+	}
+	out = append(out, items...)
+	return sealed(out), nil
+}
+
+// Decodes a patch encoded by MarshalBinary. ErrChecksum is returned if the data was corrupted.
+func (a *Patch) UnmarshalBinary(data []byte) error {
+	body, err := unsealed(data, patchMagic, patchVersion)
+	if err != nil {
+		return err
+	}
+	r := bytes.NewReader(body)
+	n, err := binary.ReadUvarint(r)
+	if (err != nil) || (n > uint64(r.Len())) {
+		return ErrFormat
+	}
+	out := make(Patch, n)
+	for i := range out {
+		op, err := r.ReadByte()
+		if err != nil {
+			return ErrFormat
+		}
+		switch op {
+		case patchAdd:
+			out[i].Kind = Added
+		case patchRemove:
+			out[i].Kind = Removed
+		default:
+			return ErrFormat
+		}
+		count, err := binary.ReadUvarint(r)
+		if (err != nil) || (count < 1) || (count > math.MaxInt) {
+			return ErrFormat
+		}
+		out[i].Count = int(count)
+	}
+	var items EqualSet
+	err = items.UnmarshalBinary(body[len(body)-r.Len():])
+	if err != nil {
+		return err
+	}
+	if len(items) != len(out) {
+		return ErrFormat
+	}
+	for i := range out {
+		out[i].Item = items[i]
+	}
+	err = out.distinct()
+	if err != nil {
+		return err
+	}
+	*a = out
+	return nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type DeltaCase struct {
+	From EqualSet
+	To   EqualSet
+}
+
+var DeltaCases = []DeltaCase{
+	{
+		From: EqualSet{Coordinate{1, 1}, Coordinate{2, 2}, Coordinate{2, 2}},
+		To:   EqualSet{Coordinate{2, 2}, Coordinate{3, 3}, Coordinate{3, 3}, Coordinate{3, 3}},
+	},
+	{
+		From: EqualSet{Int(1), Int(1), Int(1)},
+		To:   EqualSet{Int(1)},
+	},
+	{
+		From: EqualSet{},
+		To:   EqualSet{String("a")},
+	},
+	{
+		From: EqualSet{String("a")},
+		To:   EqualSet{String("a")},
+	},
+}
+
+func TestDelta(t *testing.T) {
+	for i, c := range DeltaCases {
+		p := Delta(c.From, c.To)
+		out, err := Apply(c.From, p)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		if out.Equal(c.To) == false {
+			t.Fatalf("%v: %v not equal to %v", i, out, c.To)
+		}
+		back, err := Apply(c.To, p.Invert())
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		if back.Equal(c.From) == false {
+			t.Fatalf("%v: inverted %v not equal to %v", i, back, c.From)
+		}
+	}
+	p := Delta(DeltaCases[0].From, DeltaCases[0].To)
+	expected := Patch{
+		{Kind: Removed, Item: Coordinate{1, 1}, Count: 1},
+		{Kind: Removed, Item: Coordinate{2, 2}, Count: 1},
+		{Kind: Added, Item: Coordinate{3, 3}, Count: 3},
+	}
+	if len(p) != len(expected) {
+		t.Fatalf("unexpected patch %v", p)
+	}
+	for i := range expected {
+		if p[i] != expected[i] {
+			t.Fatalf("%v: %v, expected %v", i, p[i], expected[i])
+		}
+	}
+}
+
+func TestApplyNotPresent(t *testing.T) {
+	set := EqualSet{Int(1)}
+	_, err := Apply(set, Patch{{Kind: Removed, Item: Int(1), Count: 2}})
+	if errors.Is(err, ErrNotPresent) == false {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_, err = Apply(set, Patch{{Kind: Added, Item: Int(2), Count: 1}, {Kind: Removed, Item: Int(3), Count: 1}})
+	if errors.Is(err, ErrNotPresent) == false {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// two removals of one copy each remove more copies than the set has
+	_, err = Apply(set, Patch{{Kind: Removed, Item: Int(1), Count: 1}, {Kind: Removed, Item: Int(1), Count: 1}})
+	if errors.Is(err, ErrNotPresent) == false {
+		t.Fatalf("unexpected error %v", err)
+	}
+	out, err := Apply(set, Patch{{Kind: Added, Item: Int(1), Count: 1}, {Kind: Removed, Item: Int(1), Count: 2}})
+	if (err != nil) || (len(out) != 0) {
+		t.Fatalf("net removal rejected: %v, %v", out, err)
+	}
+	if set.Equal(EqualSet{Int(1)}) == false {
+		t.Fatal("rejected patch changed the set")
+	}
+}
+
+func TestApplyInvalidChange(t *testing.T) {
+	for _, c := range []Change{{Item: Int(1), Count: 1}, {Kind: Added, Item: Int(1)}, {Kind: Removed, Item: Int(1), Count: -1}} {
+		if _, err := Apply(EqualSet{Int(1)}, Patch{c}); err == nil {
+			t.Fatalf("applied %v", c)
+		}
+	}
+}
+
+func TestPatchDecodeRepeatedItem(t *testing.T) {
+	repeated := Patch{{Kind: Removed, Item: Int(1), Count: 1}, {Kind: Removed, Item: Int(1), Count: 1}}
+	data, err := json.Marshal(repeated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p Patch
+	if json.Unmarshal(data, &p) == nil {
+		t.Fatal("JSON patch with a repeated item decoded")
+	}
+	data, err = repeated.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.UnmarshalBinary(data) == nil {
+		t.Fatal("binary patch with a repeated item decoded")
+	}
+}
+
+func TestPatchDecodeLargeCount(t *testing.T) {
+	items, err := EqualSet{Int(1)}.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append([]byte(patchMagic), patchVersion)
+	data = binary.AppendUvarint(data, 1)
+	data = append(data, patchAdd)
+	data = binary.AppendUvarint(data, 1<<63)
+	var p Patch
+	if p.UnmarshalBinary(sealed(append(data, items...))) != ErrFormat {
+		t.Fatalf("count of 1<<63 decoded as %v", p)
+	}
+}
+
+func TestPatchCompose(t *testing.T) {
+	a := EqualSet{Int(1), Int(2), Int(2)}
+	b := EqualSet{Int(2), Int(3)}
+	c := EqualSet{Int(1), Int(2), Int(2), Int(4)}
+	p := Delta(a, b).Compose(Delta(b, c))
+	if (len(p) != 1) || (p[0] != Change{Kind: Added, Item: Int(4), Count: 1}) {
+		t.Fatalf("unexpected composed patch %v", p)
+	}
+	out, err := Apply(a, p)
+	if (err != nil) || (out.Equal(c) == false) {
+		t.Fatalf("composed patch gives %v, %v", out, err)
+	}
+	if len(p.Compose(p.Invert())) != 0 {
+		t.Fatal("patch composed with its inverse isn't empty")
+	}
+}
+
+func TestPatchEncoding(t *testing.T) {
+	for i, c := range DeltaCases {
+		p := Delta(c.From, c.To)
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		var fromJSON Patch
+		err = json.Unmarshal(data, &fromJSON)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		data, err = p.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		var fromBinary Patch
+		err = fromBinary.UnmarshalBinary(data)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		for _, decoded := range []Patch{fromJSON, fromBinary} {
+			out, err := Apply(c.From, decoded)
+			if (err != nil) || (out.Equal(c.To) == false) {
+				t.Fatalf("%v: decoded patch gives %v, %v", i, out, err)
+			}
+		}
+		data[len(data)/2] ^= 1
+		if err = fromBinary.UnmarshalBinary(data); err != ErrChecksum {
+			t.Fatalf("%v: unexpected error %v", i, err)
+		}
+	}
+	data, _ := json.Marshal(Patch{{Kind: Removed, Item: Coordinate{3, 4}, Count: 2}})
+	if string(data) != `{"type":"coord","changes":[{"op":"remove","item":{"X":3,"Y":4},"count":2}]}` {
+		t.Fatalf("unexpected JSON %v", string(data))
+	}
+}
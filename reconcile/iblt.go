@@ -0,0 +1,139 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package reconcile
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// An IBLT cell holds the count of keys inserted into it and the XOR of those keys and of their check hashes. Subtracting one table from another leaves cells of the keys in only one table, and a cell with a count of 1 or -1 whose check matches its key is pure: it holds exactly one key, which can be removed from the other cells it was inserted into.
+type cell struct {
+	count   int32
+	keySum  uint64
+	hashSum uint64
+}
+
+const cellSize = 4 + 8 + 8
+
+// Mixed into a key to make its check hash.
+const checkSeed = 0x2545f4914f6cdd1d
+
+// Each key is inserted into one cell of each of the three equal parts of the table.
+const hashCount = 3
+
+type table struct {
+	cells []cell
+}
+
+func newTable(cells int, keys []uint64) table {
+	t := table{make([]cell, cells)}
+	for _, key := range keys {
+		t.insert(key, 1)
+	}
+	return t
+}
+
+func (a table) insert(key uint64, count int32) {
+	check := mix(key ^ checkSeed)
+	part := uint64(len(a.cells) / hashCount)
+	for i := uint64(0); i < hashCount; i++ {
+		c := &a.cells[i*part+mix(key+i)%part]
+		c.count += count
+		c.keySum ^= key
+		c.hashSum ^= check
+	}
+}
+
+// Returns the table of keys in this table minus the keys in the other table. The receiver is modified.
+func (a table) subtract(other table) table {
+	for i := range a.cells {
+		a.cells[i].count -= other.cells[i].count
+		a.cells[i].keySum ^= other.cells[i].keySum
+		a.cells[i].hashSum ^= other.cells[i].hashSum
+	}
+	return a
+}
+
+// Lists the keys only in the subtracted-from table and the keys only in the other table. If the cells can't all be peeled then false is returned. The table is emptied.
+func (a table) decode() (added, removed []uint64, ok bool) {
+	pure := make([]int, 0, len(a.cells))
+	for i := range a.cells {
+		if a.pure(i) {
+			pure = append(pure, i)
+		}
+	}
+	for len(pure) > 0 {
+		i := pure[len(pure)-1]
+		pure = pure[:len(pure)-1]
+		if a.pure(i) == false {
+			continue
+		}
+		c := a.cells[i]
+		if c.count == 1 {
+			added = append(added, c.keySum)
+		} else {
+			removed = append(removed, c.keySum)
+		}
+		a.insert(c.keySum, -c.count)
+		part := len(a.cells) / hashCount
+		for j := 0; j < hashCount; j++ {
+			k := j*part + int(mix(c.keySum+uint64(j))%uint64(part))
+			if a.pure(k) {
+				pure = append(pure, k)
+			}
+		}
+	}
+	for _, c := range a.cells {
+		if c != (cell{}) {
+			return nil, nil, false
+		}
+	}
+	return added, removed, true
+}
+
+func (a table) pure(i int) bool {
+	c := a.cells[i]
+	return ((c.count == 1) || (c.count == -1)) && (mix(c.keySum^checkSeed) == c.hashSum)
+}
+
+func (a table) encode() []byte {
+	out := make([]byte, 0, len(a.cells)*cellSize)
+	for _, c := range a.cells {
+		out = binary.BigEndian.AppendUint32(out, uint32(c.count))
+		out = binary.BigEndian.AppendUint64(out, c.keySum)
+		out = binary.BigEndian.AppendUint64(out, c.hashSum)
+	}
+	return out
+}
+
+func decodeTable(data []byte) (table, error) {
+	n := len(data) / cellSize
+	if (len(data)%cellSize != 0) || (n == 0) || (n%hashCount != 0) {
+		return table{}, errors.New("reconcile: invalid IBLT")
+	}
+	t := table{make([]cell, n)}
+	for i := range t.cells {
+		t.cells[i] = cell{
+			count:   int32(binary.BigEndian.Uint32(data)),
+			keySum:  binary.BigEndian.Uint64(data[4:]),
+			hashSum: binary.BigEndian.Uint64(data[12:]),
+		}
+		data = data[cellSize:]
+	}
+	return t, nil
+}
+
+// The splitmix64 finalizer spreads the item hashes, which may be as simple as the item's integer value, over the cells.
+func mix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
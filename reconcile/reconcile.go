@@ -0,0 +1,293 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package reconcile finds the items each of two sets holds that the other lacks, exchanging data in proportion to the size of the difference instead of the size of the sets.
+//
+// One side calls Initiate and the other Respond on the two ends of a connection such as a net.Conn. The initiator sends an invertible Bloom lookup table (IBLT) of the hashes of its items. The responder subtracts a table of its own hashes and decodes the hashes that are in only one set. If the table is too small to decode, both sides retry with one four times larger. The responder then sends the items the initiator lacks and asks for the items it lacks, which the initiator sends.
+//
+// Items must be unordered.Hashable, and registered with unordered.RegisterType so they can be sent with the binary set encoding. Distinct items with the same Hash are treated as the same item, and duplicates in a set are ignored.
+package reconcile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/pciet/unordered"
+)
+
+// The Result of a reconciliation is the two one-sided differences and the count of bytes sent and received by this side.
+type Result struct {
+	// Items this side has that the other side lacks.
+	Mine unordered.EqualSet
+	// Items the other side has that this side lacks.
+	Theirs unordered.EqualSet
+	// The count of IBLT sizes tried.
+	Rounds   int
+	Sent     int64
+	Received int64
+}
+
+// The first IBLT has this many cells, and the largest tried has at most MaxCells.
+const (
+	InitialCells = 48
+	MaxCells     = 1 << 22
+)
+
+// The limit used instead of MaxCells, so tests can reach it with small sets.
+var maxCells = MaxCells
+
+// ErrTooLarge is returned by both sides when the difference can't be decoded with an IBLT of MaxCells.
+var ErrTooLarge = errors.New("reconcile: difference too large")
+
+const (
+	tableMessage = iota + 1
+	retryMessage
+	resultMessage
+	itemsMessage
+	// Sent instead of a larger table or a retry when the next table would be larger than the limit.
+	abortMessage
+)
+
+// Reconciles the set with the set of the Respond call on the other end of the connection.
+func Initiate(conn io.ReadWriter, set unordered.EqualSet) (result Result, err error) {
+	c := newCounter(conn)
+	defer c.count(&result)
+	hashes, items, err := hashItems(set)
+	if err != nil {
+		return result, err
+	}
+	for cells := InitialCells; ; cells *= 4 {
+		if cells > maxCells {
+			c.send(abortMessage, nil)
+			return result, ErrTooLarge
+		}
+		result.Rounds++
+		err = c.send(tableMessage, newTable(cells, hashes).encode())
+		if err != nil {
+			return result, err
+		}
+		kind, payload, err := c.receive(cells)
+		if err != nil {
+			return result, err
+		}
+		if kind == retryMessage {
+			continue
+		}
+		if kind == abortMessage {
+			return result, ErrTooLarge
+		}
+		if kind != resultMessage {
+			return result, fmt.Errorf("reconcile: unexpected message %v", kind)
+		}
+		// the result is the hashes the responder lacks followed by the items the initiator lacks
+		wanted, rest, err := decodeHashes(payload)
+		if err != nil {
+			return result, err
+		}
+		result.Theirs, err = decodeItems(rest)
+		if err != nil {
+			return result, err
+		}
+		result.Mine, err = pick(items, wanted)
+		if err != nil {
+			return result, err
+		}
+		data, err := result.Mine.MarshalBinary()
+		if err != nil {
+			return result, err
+		}
+		return result, c.send(itemsMessage, data)
+	}
+}
+
+// Reconciles the set with the set of the Initiate call on the other end of the connection.
+func Respond(conn io.ReadWriter, set unordered.EqualSet) (result Result, err error) {
+	c := newCounter(conn)
+	defer c.count(&result)
+	hashes, items, err := hashItems(set)
+	if err != nil {
+		return result, err
+	}
+	for cells := InitialCells; ; cells *= 4 {
+		kind, payload, err := c.receive(cells)
+		if err != nil {
+			return result, err
+		}
+		if kind == abortMessage {
+			return result, ErrTooLarge
+		}
+		if kind != tableMessage {
+			return result, fmt.Errorf("reconcile: unexpected message %v", kind)
+		}
+		result.Rounds++
+		theirs, err := decodeTable(payload)
+		if err != nil {
+			return result, err
+		}
+		if len(theirs.cells) != cells {
+			return result, fmt.Errorf("reconcile: IBLT of %v cells, expected %v", len(theirs.cells), cells)
+		}
+		onlyTheirs, onlyMine, ok := theirs.subtract(newTable(len(theirs.cells), hashes)).decode()
+		if ok == false {
+			if len(theirs.cells)*4 > maxCells {
+				c.send(abortMessage, nil)
+				return result, ErrTooLarge
+			}
+			err = c.send(retryMessage, nil)
+			if err != nil {
+				return result, err
+			}
+			continue
+		}
+		result.Mine, err = pick(items, onlyMine)
+		if err != nil {
+			return result, err
+		}
+		data, err := result.Mine.MarshalBinary()
+		if err != nil {
+			return result, err
+		}
+		err = c.send(resultMessage, append(encodeHashes(onlyTheirs), data...))
+		if err != nil {
+			return result, err
+		}
+		kind, payload, err = c.receive(cells)
+		if err != nil {
+			return result, err
+		}
+		if kind != itemsMessage {
+			return result, fmt.Errorf("reconcile: unexpected message %v", kind)
+		}
+		result.Theirs, err = decodeItems(payload)
+		return result, err
+	}
+}
+
+func hashItems(set unordered.EqualSet) ([]uint64, map[uint64]unordered.Comparable, error) {
+	hashes := make([]uint64, 0, len(set))
+	items := make(map[uint64]unordered.Comparable, len(set))
+	for _, item := range set {
+		h, ok := item.(unordered.Hashable)
+		if ok == false {
+			return nil, nil, fmt.Errorf("reconcile: item %v is not Hashable", item)
+		}
+		hash := h.Hash()
+		if _, has := items[hash]; has {
+			continue
+		}
+		items[hash] = item
+		hashes = append(hashes, hash)
+	}
+	return hashes, items, nil
+}
+
+func pick(items map[uint64]unordered.Comparable, hashes []uint64) (unordered.EqualSet, error) {
+	out := make(unordered.EqualSet, 0, len(hashes))
+	for _, h := range hashes {
+		item, has := items[h]
+		if has == false {
+			return nil, fmt.Errorf("reconcile: no item with hash %x", h)
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+func decodeItems(data []byte) (unordered.EqualSet, error) {
+	var out unordered.EqualSet
+	err := out.UnmarshalBinary(data)
+	return out, err
+}
+
+func encodeHashes(hashes []uint64) []byte {
+	out := binary.AppendUvarint(nil, uint64(len(hashes)))
+	for _, h := range hashes {
+		out = binary.BigEndian.AppendUint64(out, h)
+	}
+	return out
+}
+
+func decodeHashes(data []byte) ([]uint64, []byte, error) {
+	n, k := binary.Uvarint(data)
+	if (k <= 0) || (n > uint64(len(data)-k)/8) {
+		return nil, nil, errors.New("reconcile: invalid hash list")
+	}
+	data = data[k:]
+	out := make([]uint64, n)
+	for i := range out {
+		out[i] = binary.BigEndian.Uint64(data)
+		data = data[8:]
+	}
+	return out, data, nil
+}
+
+// A counter frames messages on the connection and counts the bytes sent and received. A message is a 4 byte big-endian length of the kind and payload, a kind byte, and the payload.
+type counter struct {
+	r        *bufio.Reader
+	w        io.Writer
+	sent     int64
+	received int64
+}
+
+func newCounter(conn io.ReadWriter) *counter {
+	return &counter{
+		r: bufio.NewReader(conn),
+		w: conn,
+	}
+}
+
+func (a *counter) send(kind byte, payload []byte) error {
+	out := make([]byte, 5, 5+len(payload))
+	binary.BigEndian.PutUint32(out, uint32(1+len(payload)))
+	out[4] = kind
+	out = append(out, payload...)
+	n, err := a.w.Write(out)
+	a.sent += int64(n)
+	return err
+}
+
+// The length limit of retry and abort messages, which have no payload.
+const controlLimit = 16
+
+// Receives a message of the round where the table has the count of cells. The length is limited by the kind, and items are read as they arrive so a peer can't make the receiver allocate more than it sends.
+func (a *counter) receive(cells int) (byte, []byte, error) {
+	var header [5]byte
+	n, err := io.ReadFull(a.r, header[:])
+	a.received += int64(n)
+	if err != nil {
+		return 0, nil, err
+	}
+	l := int64(binary.BigEndian.Uint32(header[:]))
+	limit := int64(controlLimit)
+	switch header[4] {
+	case tableMessage:
+		limit = 1 + int64(cells)*cellSize
+	case resultMessage, itemsMessage:
+		limit = math.MaxUint32
+	}
+	if (l < 1) || (l > limit) {
+		return 0, nil, fmt.Errorf("reconcile: invalid message length %v", l)
+	}
+	var payload bytes.Buffer
+	m, err := io.CopyN(&payload, a.r, l-1)
+	a.received += m
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return header[4], payload.Bytes(), err
+}
+
+func (a *counter) count(into *Result) {
+	into.Sent = a.sent
+	into.Received = a.received
+}
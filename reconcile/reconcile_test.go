@@ -0,0 +1,176 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package reconcile
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/pciet/unordered"
+)
+
+type ID uint64
+
+func (a ID) Equal(to unordered.Comparable) bool { return a == to.(ID) }
+func (a ID) Hash() uint64                       { return uint64(a) }
+
+func init() {
+	unordered.RegisterType("id", ID(0))
+}
+
+type outcome struct {
+	result Result
+	err    error
+}
+
+func reconcile(t *testing.T, a, b unordered.EqualSet) (Result, Result) {
+	t.Helper()
+	left, right := net.Pipe()
+	defer left.Close()
+	defer right.Close()
+	done := make(chan outcome)
+	go func() {
+		r, err := Respond(right, b)
+		done <- outcome{r, err}
+	}()
+	ra, err := Initiate(left, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := <-done
+	if o.err != nil {
+		t.Fatal(o.err)
+	}
+	return ra, o.result
+}
+
+func TestReconcile(t *testing.T) {
+	const shared = 5000
+	r := rand.New(rand.NewSource(1))
+	var previous int64
+	for _, d := range []int{0, 1, 10, 100, 1000} {
+		a := make(unordered.EqualSet, 0, shared+d)
+		b := make(unordered.EqualSet, 0, shared+d)
+		for i := 0; i < shared; i++ {
+			a = append(a, ID(r.Uint64()))
+			b = append(b, a[i])
+		}
+		onlyA := make(unordered.EqualSet, 0, d)
+		onlyB := make(unordered.EqualSet, 0, d)
+		for i := 0; i < d; i++ {
+			id := ID(r.Uint64())
+			if i%3 == 0 {
+				onlyB = append(onlyB, id)
+				b = append(b, id)
+			} else {
+				onlyA = append(onlyA, id)
+				a = append(a, id)
+			}
+		}
+		r.Shuffle(len(b), func(i, j int) { b[i], b[j] = b[j], b[i] })
+		ra, rb := reconcile(t, a, b)
+		if (ra.Mine.Equal(onlyA) == false) || (ra.Theirs.Equal(onlyB) == false) {
+			t.Fatalf("difference %v: initiator got mine %v theirs %v", d, len(ra.Mine), len(ra.Theirs))
+		}
+		if (rb.Mine.Equal(onlyB) == false) || (rb.Theirs.Equal(onlyA) == false) {
+			t.Fatalf("difference %v: responder got mine %v theirs %v", d, len(rb.Mine), len(rb.Theirs))
+		}
+		if (ra.Sent != rb.Received) || (ra.Received != rb.Sent) {
+			t.Fatalf("difference %v: byte counts disagree %+v %+v", d, ra, rb)
+		}
+		total := ra.Sent + ra.Received
+		t.Logf("difference %4v: %7v bytes in %v rounds", d, total, ra.Rounds)
+		if total < previous {
+			t.Fatalf("difference %v: %v bytes is less than %v for a smaller difference", d, total, previous)
+		}
+		previous = total
+		// a whole set is at least 8 bytes for each item
+		if (d <= 100) && (total > 8*shared/2) {
+			t.Fatalf("difference %v: %v bytes isn't proportional to the difference", d, total)
+		}
+	}
+}
+
+func TestReconcileEmpty(t *testing.T) {
+	a := unordered.EqualSet{ID(1), ID(2), ID(2)}
+	ra, rb := reconcile(t, a, unordered.EqualSet{})
+	if (ra.Mine.Equal(unordered.EqualSet{ID(1), ID(2)}) == false) || (len(ra.Theirs) != 0) {
+		t.Fatal(ra.Mine, ra.Theirs)
+	}
+	if (rb.Theirs.Equal(unordered.EqualSet{ID(1), ID(2)}) == false) || (len(rb.Mine) != 0) {
+		t.Fatal(rb.Mine, rb.Theirs)
+	}
+}
+
+func TestTableDecode(t *testing.T) {
+	a := newTable(48, []uint64{1, 2, 3, 4})
+	added, removed, ok := a.subtract(newTable(48, []uint64{3, 4, 5})).decode()
+	if (ok == false) || (len(added) != 2) || (len(removed) != 1) || (removed[0] != 5) {
+		t.Fatal(added, removed, ok)
+	}
+	keys := make([]uint64, 200)
+	for i := range keys {
+		keys[i] = uint64(i)
+	}
+	_, _, ok = newTable(48, keys).decode()
+	if ok {
+		t.Fatal("decoded 200 keys from 48 cells")
+	}
+}
+
+func TestReconcileTooLarge(t *testing.T) {
+	defer func(limit int) { maxCells = limit }(maxCells)
+	maxCells = 4 * InitialCells
+	a := make(unordered.EqualSet, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		a = append(a, ID(i))
+	}
+	left, right := net.Pipe()
+	defer left.Close()
+	defer right.Close()
+	done := make(chan outcome)
+	go func() {
+		r, err := Respond(right, unordered.EqualSet{})
+		done <- outcome{r, err}
+	}()
+	_, err := Initiate(left, a)
+	if err != ErrTooLarge {
+		t.Fatal("initiator", err)
+	}
+	o := <-done
+	if o.err != ErrTooLarge {
+		t.Fatal("responder", o.err)
+	}
+	if o.result.Rounds != 2 {
+		t.Fatal(o.result.Rounds, "rounds")
+	}
+}
+
+func TestReconcileMessageLimits(t *testing.T) {
+	for _, kind := range []byte{tableMessage, retryMessage} {
+		left, right := net.Pipe()
+		done := make(chan outcome)
+		go func() {
+			r, err := Respond(right, unordered.EqualSet{})
+			done <- outcome{r, err}
+		}()
+		// a table larger than the first round's, and a retry with a payload
+		header := binary.BigEndian.AppendUint32(nil, 1+InitialCells*cellSize+1)
+		go left.Write(append(header, kind))
+		o := <-done
+		if (o.err == nil) || (strings.Contains(o.err.Error(), "invalid message length") == false) {
+			t.Fatal(kind, o.err)
+		}
+		left.Close()
+		right.Close()
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var registry = struct {
+	sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}{
+	byName: make(map[string]reflect.Type),
+	byType: make(map[reflect.Type]string),
+}
+
+// Registers the concrete type of the sample item with a name so sets of that type can be decoded by the encodings in this package. The name is written into encoded sets in place of the Go type. Registering the same name or type twice panics, like gob.Register.
+//
+// Call RegisterType in an init function of the package that defines the item type:
+//     func init() {
+//         unordered.RegisterType("coord", Coordinate{})
+//     }
+func RegisterType(name string, sample Item) {
+	if name == "" {
+		panic("unordered: RegisterType called with empty name")
+	}
+	if sample == nil {
+		panic("unordered: RegisterType called with nil sample")
+	}
+	t := reflect.TypeOf(sample)
+	registry.Lock()
+	defer registry.Unlock()
+	if had, has := registry.byName[name]; has {
+		panic(fmt.Sprintf("unordered: name %q registered for both %v and %v", name, had, t))
+	}
+	if had, has := registry.byType[t]; has {
+		panic(fmt.Sprintf("unordered: type %v registered as both %q and %q", t, had, name))
+	}
+	registry.byName[name] = t
+	registry.byType[t] = name
+}
+
+func registeredType(name string) (reflect.Type, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	t, has := registry.byName[name]
+	if has == false {
+		return nil, fmt.Errorf("unordered: type name %q not registered", name)
+	}
+	return t, nil
+}
+
+func registeredName(t reflect.Type) (string, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	name, has := registry.byType[t]
+	if has == false {
+		return "", fmt.Errorf("unordered: type %v not registered", t)
+	}
+	return name, nil
+}
+
+// Returns the registered name of the type shared by every item of the set, or the empty string for an empty set.
+func (a Set) registeredName() (string, error) {
+	t := a.typeof()
+	if t == nil {
+		return "", nil
+	}
+	for i, item := range a {
+		if reflect.TypeOf(item) != t {
+			return "", fmt.Errorf("unordered: item %v type %v doesn't match set type %v", i, reflect.TypeOf(item), t)
+		}
+	}
+	return registeredName(t)
+}
+
+// Returns a pointer to a new zero value that an item of the type can be decoded into, and a function that gets the item from that pointer.
+func newItem(t reflect.Type) (interface{}, func() Item) {
+	if t.Kind() == reflect.Ptr {
+		v := reflect.New(t.Elem())
+		return v.Interface(), func() Item { return v.Interface() }
+	}
+	v := reflect.New(t)
+	return v.Interface(), func() Item { return v.Elem().Interface() }
+}
+
+func comparableType(t reflect.Type) error {
+	if t.Implements(reflect.TypeOf((*Comparable)(nil)).Elem()) == false {
+		return fmt.Errorf("unordered: registered type %v is not Comparable", t)
+	}
+	return nil
+}
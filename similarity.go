@@ -0,0 +1,126 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"math"
+)
+
+// Returns the Jaccard index of the distinct items of the sets, the count of items in both divided by the count of items in either. Two empty sets have an index of 1. Duplicates are ignored; MinHash estimates this index.
+func Jaccard(a, b EqualSet) float64 {
+	both, inA, inB := distinctCounts(a, b)
+	either := inA + inB - both
+	if either == 0 {
+		return 1
+	}
+	return float64(both) / float64(either)
+}
+
+// Returns the Sørensen–Dice coefficient of the distinct items of the sets, twice the count of items in both divided by the sum of the counts of each set's items. Two empty sets have a coefficient of 1.
+func Dice(a, b EqualSet) float64 {
+	both, inA, inB := distinctCounts(a, b)
+	if inA+inB == 0 {
+		return 1
+	}
+	return 2 * float64(both) / float64(inA+inB)
+}
+
+// Returns the overlap coefficient of the distinct items of the sets, the count of items in both divided by the count of items in the smaller set. It's 1 when one set's items are a subset of the other's. Two empty sets have a coefficient of 1, and an empty and non-empty set 0.
+func Overlap(a, b EqualSet) float64 {
+	both, inA, inB := distinctCounts(a, b)
+	if (inA == 0) && (inB == 0) {
+		return 1
+	}
+	if (inA == 0) || (inB == 0) {
+		return 0
+	}
+	if inB < inA {
+		inA = inB
+	}
+	return float64(both) / float64(inA)
+}
+
+// Returns the cosine similarity of the sets as vectors of item counts, so duplicates weigh an item more. Two empty sets have a similarity of 1, and an empty and non-empty set 0.
+func Cosine(a, b EqualSet) float64 {
+	if (len(a) == 0) && (len(b) == 0) {
+		return 1
+	}
+	var dot, sumA, sumB float64
+	for _, c := range tally(a, b) {
+		dot += float64(c.a) * float64(c.b)
+		sumA += float64(c.a) * float64(c.a)
+		sumB += float64(c.b) * float64(c.b)
+	}
+	if (sumA == 0) || (sumB == 0) {
+		return 0
+	}
+	return dot / (math.Sqrt(sumA) * math.Sqrt(sumB))
+}
+
+// Returns the Jaccard index of the sets as multisets, the sum over the distinct items of the lesser count in either set divided by the sum of the greater. It's 1 exactly when the sets are Equal. Two empty sets have an index of 1.
+func MultisetJaccard(a, b EqualSet) float64 {
+	if (len(a) == 0) && (len(b) == 0) {
+		return 1
+	}
+	mins, maxes := 0, 0
+	for _, c := range tally(a, b) {
+		if c.a < c.b {
+			mins += c.a
+			maxes += c.b
+		} else {
+			mins += c.b
+			maxes += c.a
+		}
+	}
+	return float64(mins) / float64(maxes)
+}
+
+// A tallied item has its count of copies in each of two sets.
+type tallied struct {
+	item Comparable
+	a, b int
+}
+
+// Counts the copies of each distinct item in the two sets in one pass over each, finding items with the shared index.
+func tally(a, b EqualSet) []tallied {
+	out := make([]tallied, 0, len(a)+len(b))
+	positions := newIndex()
+	find := func(the Comparable) int {
+		i, found := positions.find(the)
+		if found {
+			return i
+		}
+		out = append(out, tallied{item: the})
+		positions.addAt(the, len(out)-1)
+		return len(out) - 1
+	}
+	for _, item := range a {
+		out[find(item)].a++
+	}
+	for _, item := range b {
+		out[find(item)].b++
+	}
+	return out
+}
+
+// Returns the counts of distinct items in both sets, in the first set, and in the second set.
+func distinctCounts(a, b EqualSet) (both, inA, inB int) {
+	for _, c := range tally(a, b) {
+		if c.a > 0 {
+			inA++
+		}
+		if c.b > 0 {
+			inB++
+		}
+		if (c.a > 0) && (c.b > 0) {
+			both++
+		}
+	}
+	return both, inA, inB
+}
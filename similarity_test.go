@@ -0,0 +1,69 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"math"
+	"testing"
+)
+
+func TestJaccard(t *testing.T) {
+	for _, c := range []struct {
+		a, b     EqualSet
+		expected float64
+	}{
+		{EqualSet{}, EqualSet{}, 1},
+		{EqualSet{Int(1)}, EqualSet{}, 0},
+		{EqualSet{Int(1), Int(2), Int(3)}, EqualSet{Int(2), Int(3), Int(4)}, 2.0 / 4},
+		{EqualSet{Int(1), Int(1), Int(2)}, EqualSet{Int(2), Int(1)}, 1},
+		{EqualSet{Coordinate{1, 2}, Coordinate{3, 4}}, EqualSet{Coordinate{3, 4}, Coordinate{5, 6}, Coordinate{7, 8}}, 1.0 / 4},
+		{EqualSet{String("a"), String("b")}, EqualSet{String("b"), String("b")}, 1.0 / 2},
+	} {
+		j := Jaccard(c.a, c.b)
+		if j != c.expected {
+			t.Fatalf("Jaccard(%v, %v) = %v, expected %v", c.a, c.b, j, c.expected)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	for _, c := range []struct {
+		a, b                                     EqualSet
+		jaccard, dice, overlap, cosine, multiset float64
+	}{
+		{EqualSet{}, EqualSet{}, 1, 1, 1, 1, 1},
+		{EqualSet{Int(1)}, EqualSet{}, 0, 0, 0, 0, 0},
+		// distinct {1,2,3} and {2,3,4,5}, counts (1,1,1,0,0) and (0,1,1,1,1)
+		{EqualSet{Int(1), Int(2), Int(3)}, EqualSet{Int(2), Int(3), Int(4), Int(5)}, 2.0 / 5, 4.0 / 7, 2.0 / 3, 2 / (math.Sqrt(3) * 2), 2.0 / 5},
+		// distinct {1,2}, counts (3,1) and (1,1)
+		{EqualSet{Int(1), Int(1), Int(1), Int(2)}, EqualSet{Int(2), Int(1)}, 1, 1, 1, 4 / (math.Sqrt(10) * math.Sqrt(2)), 2.0 / 4},
+		// distinct {(1,2)} and {(1,2),(3,4)}, counts (2,0) and (1,2)
+		{EqualSet{Coordinate{1, 2}, Coordinate{1, 2}}, EqualSet{Coordinate{1, 2}, Coordinate{3, 4}, Coordinate{3, 4}}, 1.0 / 2, 2.0 / 3, 1, 2 / (2 * math.Sqrt(5)), 1.0 / 4},
+		// distinct {a,b,c} and {c}, counts (1,1,2) and (0,0,3)
+		{EqualSet{String("a"), String("b"), String("c"), String("c")}, EqualSet{String("c"), String("c"), String("c")}, 1.0 / 3, 2.0 / 4, 1, 6 / (math.Sqrt(6) * 3), 2.0 / 5},
+	} {
+		for _, m := range []struct {
+			name     string
+			fn       func(a, b EqualSet) float64
+			expected float64
+		}{
+			{"Jaccard", Jaccard, c.jaccard},
+			{"Dice", Dice, c.dice},
+			{"Overlap", Overlap, c.overlap},
+			{"Cosine", Cosine, c.cosine},
+			{"MultisetJaccard", MultisetJaccard, c.multiset},
+		} {
+			for _, v := range []float64{m.fn(c.a, c.b), m.fn(c.b, c.a)} {
+				if math.Abs(v-m.expected) > 1e-12 {
+					t.Fatalf("%v(%v, %v) = %v, expected %v", m.name, c.a, c.b, v, m.expected)
+				}
+			}
+		}
+	}
+}
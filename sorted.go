@@ -0,0 +1,381 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+// A SortedSet keeps Ordered items sorted, with the count of copies of each distinct item, and answers range and order statistic queries an EqualSet can't answer efficiently. Unlike EqualSet the Add and Remove methods modify the receiver. Create one with NewSortedSet.
+//
+// The set is an indexable skip list: each link records the count of copies it skips, so Rank and Select take O(log n) expected time like Add, Remove, Has, Floor, and Ceiling. Union, Intersection, SymmetricDifference, and Diff merge the two sorted lists in linear time; Diff keeps every copy of the items only one set has, like EqualSet.Diff. Ranks and positions count duplicates.
+type SortedSet struct {
+	head     *skipNode
+	level    int
+	len      uint64
+	distinct int
+	random   uint64
+}
+
+// A skipNode is a distinct item and its count with links to the following node at each of its levels.
+type skipNode struct {
+	counted
+	next []skipLink
+}
+
+// The span of a link is the count of copies in the nodes after the linking node up to and including the linked node, or to the end of the set for a nil link.
+type skipLink struct {
+	node *skipNode
+	span uint64
+}
+
+const maxSkipLevel = 32
+
+// Creates a SortedSet with the items of the set, which must all be Ordered.
+func NewSortedSet(from EqualSet) *SortedSet {
+	out := &SortedSet{
+		head:   &skipNode{next: make([]skipLink, maxSkipLevel)},
+		level:  1,
+		random: 1,
+	}
+	for _, item := range from {
+		o, ok := item.(Ordered)
+		if asserting {
+			if ok == false {
+				panic("unordered: item is not Ordered")
+			}
+		}
+		out.Add(o)
+	}
+	return out
+}
+
+// Adds a copy of the item.
+func (a *SortedSet) Add(the Ordered) {
+	a.AddCount(the, 1)
+}
+
+// Adds n copies of the item.
+func (a *SortedSet) AddCount(the Ordered, n uint64) {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	if n == 0 {
+		return
+	}
+	var update [maxSkipLevel]*skipNode
+	var rank [maxSkipLevel]uint64
+	found := a.path(the, &update, &rank)
+	if found != nil {
+		found.count += n
+		for i := 0; i < a.level; i++ {
+			update[i].next[i].span += n
+		}
+		a.len += n
+		return
+	}
+	a.link(&update, &rank, the, n)
+}
+
+// Inserts a new node after update[0], which is at position rank[0].
+func (a *SortedSet) link(update *[maxSkipLevel]*skipNode, rank *[maxSkipLevel]uint64, the Ordered, n uint64) {
+	level := a.randomLevel()
+	for ; a.level < level; a.level++ {
+		update[a.level] = a.head
+		rank[a.level] = 0
+		a.head.next[a.level].span = a.len
+	}
+	node := &skipNode{counted: counted{item: the, count: n}, next: make([]skipLink, level)}
+	for i := 0; i < level; i++ {
+		before := &update[i].next[i]
+		node.next[i] = skipLink{before.node, before.span - (rank[0] - rank[i])}
+		*before = skipLink{node, rank[0] - rank[i] + n}
+	}
+	for i := level; i < a.level; i++ {
+		update[i].next[i].span += n
+	}
+	a.len += n
+	a.distinct++
+}
+
+// Removes one copy of the item. If the set doesn't have the item then false is returned.
+func (a *SortedSet) Remove(the Ordered) bool {
+	return a.remove(the, 1) == 1
+}
+
+// Removes all copies of the item and returns the count removed.
+func (a *SortedSet) RemoveAll(the Ordered) uint64 {
+	return a.remove(the, 0)
+}
+
+// Removes n copies of the item, or all copies if n is 0.
+func (a *SortedSet) remove(the Ordered, n uint64) uint64 {
+	var update [maxSkipLevel]*skipNode
+	var rank [maxSkipLevel]uint64
+	found := a.path(the, &update, &rank)
+	if found == nil {
+		return 0
+	}
+	if (n == 0) || (n >= found.count) {
+		n = found.count
+	}
+	a.len -= n
+	if n < found.count {
+		found.count -= n
+		for i := 0; i < a.level; i++ {
+			update[i].next[i].span -= n
+		}
+		return n
+	}
+	for i := 0; i < a.level; i++ {
+		if i < len(found.next) {
+			update[i].next[i] = skipLink{found.next[i].node, update[i].next[i].span + found.next[i].span - n}
+		} else {
+			update[i].next[i].span -= n
+		}
+	}
+	for (a.level > 1) && (a.head.next[a.level-1].node == nil) {
+		a.level--
+	}
+	a.distinct--
+	return n
+}
+
+// Finds the last node at each level that's less than the item, and its position. The node of the item is returned if the set has it.
+func (a *SortedSet) path(the Ordered, update *[maxSkipLevel]*skipNode, rank *[maxSkipLevel]uint64) *skipNode {
+	n := a.head
+	position := uint64(0)
+	for i := a.level - 1; i >= 0; i-- {
+		for (n.next[i].node != nil) && n.next[i].node.item.Less(the) {
+			position += n.next[i].span
+			n = n.next[i].node
+		}
+		update[i] = n
+		rank[i] = position
+	}
+	if next := n.next[0].node; (next != nil) && next.item.Equal(the) {
+		return next
+	}
+	return nil
+}
+
+// Returns the last node less than the item, which is the head if there isn't one, and the count of copies up to and including it.
+func (a *SortedSet) before(the Ordered) (*skipNode, uint64) {
+	n := a.head
+	position := uint64(0)
+	for i := a.level - 1; i >= 0; i-- {
+		for (n.next[i].node != nil) && n.next[i].node.item.Less(the) {
+			position += n.next[i].span
+			n = n.next[i].node
+		}
+	}
+	return n, position
+}
+
+// A level of 1 plus one for each of repeated 1 in 4 chances.
+func (a *SortedSet) randomLevel() int {
+	a.random ^= a.random << 13
+	a.random ^= a.random >> 7
+	a.random ^= a.random << 17
+	level := 1
+	for r := a.random; (r&3 == 0) && (level < maxSkipLevel); r >>= 2 {
+		level++
+	}
+	return level
+}
+
+// Returns the count of copies of the item.
+func (a *SortedSet) Count(the Ordered) uint64 {
+	n, _ := a.before(the)
+	if next := n.next[0].node; (next != nil) && next.item.Equal(the) {
+		return next.count
+	}
+	return 0
+}
+
+// If the set has the item then true is returned.
+func (a *SortedSet) Has(the Ordered) bool {
+	return a.Count(the) > 0
+}
+
+// Returns the count of items in the set, including duplicates.
+func (a *SortedSet) Len() uint64 {
+	return a.len
+}
+
+// Returns the count of distinct items in the set.
+func (a *SortedSet) Distinct() int {
+	return a.distinct
+}
+
+// Returns the count of items in the set less than the item, which is the position of its first copy if the set has it.
+func (a *SortedSet) Rank(the Ordered) uint64 {
+	_, position := a.before(the)
+	return position
+}
+
+// Returns the item at the position, counting from 0 in ascending order with duplicates. The position must be less than Len.
+func (a *SortedSet) Select(position uint64) Ordered {
+	if asserting {
+		if position >= a.len {
+			panic("unordered: position out of range")
+		}
+	}
+	n := a.head
+	consumed := uint64(0)
+	for i := a.level - 1; i >= 0; i-- {
+		for (n.next[i].node != nil) && (consumed+n.next[i].span <= position) {
+			consumed += n.next[i].span
+			n = n.next[i].node
+		}
+	}
+	return n.next[0].node.item
+}
+
+// Returns the greatest item not greater than the argument. If there isn't one then false is returned.
+func (a *SortedSet) Floor(the Ordered) (Ordered, bool) {
+	n, _ := a.before(the)
+	if next := n.next[0].node; (next != nil) && next.item.Equal(the) {
+		return next.item, true
+	}
+	if n == a.head {
+		return nil, false
+	}
+	return n.item, true
+}
+
+// Returns the least item not less than the argument. If there isn't one then false is returned.
+func (a *SortedSet) Ceiling(the Ordered) (Ordered, bool) {
+	n, _ := a.before(the)
+	if next := n.next[0].node; next != nil {
+		return next.item, true
+	}
+	return nil, false
+}
+
+// Calls the function with each distinct item and its count in ascending order, starting with the first item not less than lo and stopping before the first item not less than hi, like BTreeSet.Range. A nil lo or hi is unbounded. The scan stops if the function returns false. The set must not be modified by the function.
+func (a *SortedSet) Range(lo, hi Ordered, fn func(item Ordered, count uint64) bool) {
+	if asserting {
+		if fn == nil {
+			panic("unordered: nil function")
+		}
+	}
+	n := a.head
+	if lo != nil {
+		n, _ = a.before(lo)
+	}
+	for n = n.next[0].node; n != nil; n = n.next[0].node {
+		if (hi != nil) && (n.item.Less(hi) == false) {
+			return
+		}
+		if fn(n.item, n.count) == false {
+			return
+		}
+	}
+}
+
+// Returns the items of the set as an EqualSet in ascending order, with duplicates.
+func (a *SortedSet) EqualSet() EqualSet {
+	out := make(EqualSet, 0, a.len)
+	for n := a.head.next[0].node; n != nil; n = n.next[0].node {
+		for i := uint64(0); i < n.count; i++ {
+			out = append(out, n.item)
+		}
+	}
+	return out
+}
+
+// If both sets contain an equal count of each item then true is returned.
+func (a *SortedSet) Equal(to *SortedSet) bool {
+	if (a.len != to.len) || (a.distinct != to.distinct) {
+		return false
+	}
+	n, m := a.head.next[0].node, to.head.next[0].node
+	for ; n != nil; n, m = n.next[0].node, m.next[0].node {
+		if (n.count != m.count) || (n.item.Equal(m.item) == false) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a set with the greater count of each item in either set.
+func (a *SortedSet) Union(with *SortedSet) *SortedSet {
+	return a.merge(with, func(x, y uint64) uint64 {
+		if x > y {
+			return x
+		}
+		return y
+	})
+}
+
+// Returns a set with the lesser count of each item in either set.
+func (a *SortedSet) Intersection(with *SortedSet) *SortedSet {
+	return a.merge(with, func(x, y uint64) uint64 {
+		if x < y {
+			return x
+		}
+		return y
+	})
+}
+
+// Provides a set of the items not in both sets, like EqualSet.Diff. Duplicates are not removed.
+func (a *SortedSet) Diff(from *SortedSet) *SortedSet {
+	return a.merge(from, func(x, y uint64) uint64 {
+		if (x > 0) && (y > 0) {
+			return 0
+		}
+		return x + y
+	})
+}
+
+// Returns a set with the difference between the counts of each item in both sets, the copies not matched in the other set, like Bag.SymmetricDifference.
+func (a *SortedSet) SymmetricDifference(from *SortedSet) *SortedSet {
+	return a.merge(from, func(x, y uint64) uint64 {
+		if x > y {
+			return x - y
+		}
+		return y - x
+	})
+}
+
+// Walks both sorted lists together and appends each distinct item with the function of its counts in both sets to a new set, omitting a count of 0.
+func (a *SortedSet) merge(with *SortedSet, fn func(x, y uint64) uint64) *SortedSet {
+	out := NewSortedSet(nil)
+	var tail [maxSkipLevel]*skipNode
+	var rank [maxSkipLevel]uint64
+	for i := range tail {
+		tail[i] = out.head
+	}
+	// each new node is last, so the tails are the path to it at every level
+	add := func(the Ordered, count uint64) {
+		if count == 0 {
+			return
+		}
+		out.link(&tail, &rank, the, count)
+		node := tail[0].next[0].node
+		for i := range node.next {
+			tail[i] = node
+			rank[i] = out.len
+		}
+	}
+	n, m := a.head.next[0].node, with.head.next[0].node
+	for (n != nil) || (m != nil) {
+		switch {
+		case (m == nil) || ((n != nil) && n.item.Less(m.item)):
+			add(n.item, fn(n.count, 0))
+			n = n.next[0].node
+		case (n == nil) || m.item.Less(n.item):
+			add(m.item, fn(0, m.count))
+			m = m.next[0].node
+		default:
+			add(n.item, fn(n.count, m.count))
+			n, m = n.next[0].node, m.next[0].node
+		}
+	}
+	return out
+}
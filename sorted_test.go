@@ -0,0 +1,171 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// Checks every query of the sorted set against the sorted model of its items.
+func checkSorted(t *testing.T, s *SortedSet, model []int) {
+	t.Helper()
+	sort.Ints(model)
+	if s.Len() != uint64(len(model)) {
+		t.Fatalf("length %v, expected %v", s.Len(), len(model))
+	}
+	for i, n := range model {
+		if s.Select(uint64(i)).Equal(Int(n)) == false {
+			t.Fatalf("Select(%v) = %v, expected %v", i, s.Select(uint64(i)), n)
+		}
+	}
+	for n := -1; n <= 101; n++ {
+		rank := sort.SearchInts(model, n)
+		count := sort.SearchInts(model, n+1) - rank
+		if (s.Rank(Int(n)) != uint64(rank)) || (s.Count(Int(n)) != uint64(count)) || (s.Has(Int(n)) != (count > 0)) {
+			t.Fatalf("%v: rank %v count %v, expected %v %v", n, s.Rank(Int(n)), s.Count(Int(n)), rank, count)
+		}
+		floor, ok := s.Floor(Int(n))
+		if i := sort.SearchInts(model, n+1) - 1; (ok != (i >= 0)) || (ok && (floor.Equal(Int(model[i])) == false)) {
+			t.Fatalf("Floor(%v) = %v %v", n, floor, ok)
+		}
+		ceiling, ok := s.Ceiling(Int(n))
+		if (ok != (rank < len(model))) || (ok && (ceiling.Equal(Int(model[rank])) == false)) {
+			t.Fatalf("Ceiling(%v) = %v %v", n, ceiling, ok)
+		}
+	}
+	distinct := 0
+	for i := range model {
+		if (i == 0) || (model[i] != model[i-1]) {
+			distinct++
+		}
+	}
+	if s.Distinct() != distinct {
+		t.Fatalf("%v distinct, expected %v", s.Distinct(), distinct)
+	}
+}
+
+func TestSortedSet(t *testing.T) {
+	r := rand.New(rand.NewSource(9))
+	s := NewSortedSet(EqualSet{Int(5), Int(5), Int(1)})
+	model := []int{5, 5, 1}
+	for i := 0; i < 3000; i++ {
+		n := r.Intn(100)
+		switch r.Intn(4) {
+		case 0, 1:
+			s.Add(Int(n))
+			model = append(model, n)
+		case 2:
+			removed := false
+			for j, m := range model {
+				if m == n {
+					model = append(model[:j], model[j+1:]...)
+					removed = true
+					break
+				}
+			}
+			if s.Remove(Int(n)) != removed {
+				t.Fatal("Remove", n)
+			}
+		case 3:
+			out := model[:0]
+			for _, m := range model {
+				if m != n {
+					out = append(out, m)
+				}
+			}
+			if s.RemoveAll(Int(n)) != uint64(len(model)-len(out)) {
+				t.Fatal("RemoveAll", n)
+			}
+			model = out
+		}
+		if i%100 == 0 {
+			checkSorted(t, s, model)
+		}
+	}
+	checkSorted(t, s, model)
+	for _, n := range model {
+		s.Remove(Int(n))
+	}
+	checkSorted(t, s, nil)
+}
+
+func TestSortedSetRange(t *testing.T) {
+	s := NewSortedSet(EqualSet{Coordinate{1, 1}, Coordinate{1, 2}, Coordinate{2, 0}, Coordinate{2, 0}, Coordinate{3, 5}})
+	collect := func(lo, hi Ordered) EqualSet {
+		out := EqualSet{}
+		s.Range(lo, hi, func(item Ordered, count uint64) bool {
+			for i := uint64(0); i < count; i++ {
+				out = append(out, item)
+			}
+			return true
+		})
+		return out
+	}
+	if collect(Coordinate{1, 2}, Coordinate{3, 5}).Equal(EqualSet{Coordinate{1, 2}, Coordinate{2, 0}, Coordinate{2, 0}}) == false {
+		t.Fatal(collect(Coordinate{1, 2}, Coordinate{3, 5}))
+	}
+	if collect(nil, Coordinate{1, 2}).Equal(EqualSet{Coordinate{1, 1}}) == false {
+		t.Fatal(collect(nil, Coordinate{1, 2}))
+	}
+	if len(collect(nil, nil)) != 5 {
+		t.Fatal(collect(nil, nil))
+	}
+	calls := 0
+	s.Range(nil, nil, func(Ordered, uint64) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Fatal("Range didn't stop")
+	}
+	if (s.Select(3).Equal(Coordinate{2, 0}) == false) || (s.Rank(Coordinate{3, 0}) != 4) {
+		t.Fatal("order statistics")
+	}
+}
+
+func TestSortedSetMerge(t *testing.T) {
+	r := rand.New(rand.NewSource(10))
+	for i := 0; i < 20; i++ {
+		a, b := EqualSet{}, EqualSet{}
+		for j := r.Intn(200); j > 0; j-- {
+			a = append(a, Int(r.Intn(50)))
+		}
+		for j := r.Intn(200); j > 0; j-- {
+			b = append(b, Int(r.Intn(50)))
+		}
+		sa, sb := NewSortedSet(a), NewSortedSet(b)
+		ba, bb := NewBag(a), NewBag(b)
+		for _, c := range []struct {
+			name     string
+			result   *SortedSet
+			expected *Bag
+		}{
+			{"Union", sa.Union(sb), ba.Union(bb)},
+			{"Intersection", sa.Intersection(sb), ba.Intersection(bb)},
+			{"SymmetricDifference", sa.SymmetricDifference(sb), ba.SymmetricDifference(bb)},
+			{"Diff", sa.Diff(sb), NewBag(a.Diff(b))},
+		} {
+			if NewBag(c.result.EqualSet()).Equal(c.expected) == false {
+				t.Fatalf("%v: %v, expected %v", c.name, c.result.EqualSet(), c.expected.EqualSet())
+			}
+			// the merged set's links must support later changes
+			c.result.Add(Int(25))
+			model := make([]int, 0, c.result.Len())
+			for _, item := range c.expected.EqualSet() {
+				model = append(model, int(item.(Int)))
+			}
+			checkSorted(t, c.result, append(model, 25))
+		}
+		if (sa.Union(sa).Equal(sa) == false) || (sa.SymmetricDifference(sa).Len() != 0) || (sa.Diff(sa).Len() != 0) || (sa.Equal(sb) != a.Equal(b)) {
+			t.Fatal("Equal")
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDriver is a database/sql driver with one table of id and set columns. It understands two statements:
+//     INSERT ? ?
+//     SELECT ?
+var fakeDriver = &fakeDB{rows: make(map[int64]driver.Value)}
+
+func init() {
+	sql.Register("unordered_fake", fakeDriver)
+}
+
+type fakeDB struct {
+	sync.Mutex
+	rows map[int64]driver.Value
+}
+
+func (a *fakeDB) Open(name string) (driver.Conn, error) {
+	return fakeConn{a}, nil
+}
+
+type fakeConn struct {
+	*fakeDB
+}
+
+func (a fakeConn) Prepare(query string) (driver.Stmt, error) {
+	switch query {
+	case "INSERT ? ?":
+		return fakeStmt{a.fakeDB, true}, nil
+	case "SELECT ?":
+		return fakeStmt{a.fakeDB, false}, nil
+	}
+	return nil, errors.New("unsupported query " + query)
+}
+
+func (a fakeConn) Close() error {
+	return nil
+}
+
+func (a fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions not supported")
+}
+
+type fakeStmt struct {
+	*fakeDB
+	insert bool
+}
+
+func (a fakeStmt) Close() error {
+	return nil
+}
+
+func (a fakeStmt) NumInput() int {
+	if a.insert {
+		return 2
+	}
+	return 1
+}
+
+func (a fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if a.insert == false {
+		return nil, errors.New("not an insert")
+	}
+	switch args[1].(type) {
+	case nil, string, []byte:
+	default:
+		return nil, errors.New("set column isn't text")
+	}
+	a.Lock()
+	a.rows[args[0].(int64)] = args[1]
+	a.Unlock()
+	return driver.RowsAffected(1), nil
+}
+
+func (a fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	a.Lock()
+	defer a.Unlock()
+	v, has := a.rows[args[0].(int64)]
+	return &fakeRows{value: v, done: has == false}, nil
+}
+
+type fakeRows struct {
+	value driver.Value
+	done  bool
+}
+
+func (a *fakeRows) Columns() []string {
+	return []string{"set"}
+}
+
+func (a *fakeRows) Close() error {
+	return nil
+}
+
+func (a *fakeRows) Next(dest []driver.Value) error {
+	if a.done {
+		return io.EOF
+	}
+	a.done = true
+	dest[0] = a.value
+	return nil
+}
+
+func TestSQL(t *testing.T) {
+	db, err := sql.Open("unordered_fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	in := []EqualSet{
+		{Coordinate{1, 2}, Coordinate{3, 4}, Coordinate{3, 4}},
+		{String("tag"), String("other tag")},
+		{},
+		nil,
+	}
+	for i, set := range in {
+		_, err = db.Exec("INSERT ? ?", i, set)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+	}
+	for i, set := range in {
+		var out EqualSet
+		err = db.QueryRow("SELECT ?", i).Scan(&out)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		if set == nil {
+			if out != nil {
+				t.Fatalf("%v: NULL scanned as %v", i, out)
+			}
+			continue
+		}
+		if out.Equal(set) == false {
+			t.Fatalf("%v: %v not equal to %v", i, out, set)
+		}
+	}
+}
+
+func TestSQLScanText(t *testing.T) {
+	fakeDriver.Lock()
+	fakeDriver.rows[100] = []byte("pair{(1,2), (1,2)}")
+	fakeDriver.rows[101] = "[1, 2]"
+	fakeDriver.rows[102] = "{ \"type\": \"coord\", \"items\": [{\"X\": 1, \"Y\": 2}] }"
+	fakeDriver.rows[103] = "{}"
+	fakeDriver.rows[104] = []byte("{ }")
+	fakeDriver.Unlock()
+	db, err := sql.Open("unordered_fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var out EqualSet
+	err = db.QueryRow("SELECT ?", 100).Scan(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Equal(EqualSet{Pair{1, 2}, Pair{1, 2}}) == false {
+		t.Fatalf("unexpected set %v", out)
+	}
+	err = db.QueryRow("SELECT ?", 101).Scan(&out)
+	if (err == nil) || (strings.Contains(err.Error(), "1:1") == false) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	err = db.QueryRow("SELECT ?", 102).Scan(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Equal(EqualSet{Coordinate{1, 2}}) == false {
+		t.Fatalf("unexpected set %v", out)
+	}
+	for _, row := range []int{103, 104} {
+		err = db.QueryRow("SELECT ?", row).Scan(&out)
+		if err != nil {
+			t.Fatal(row, err)
+		}
+		if (out == nil) || (len(out) != 0) {
+			t.Fatalf("%v: unexpected set %v", row, out)
+		}
+	}
+}
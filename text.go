@@ -0,0 +1,407 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// An ElementParser converts the text of one item of a set literal into the item.
+type ElementParser func(text string) (Comparable, error)
+
+// An ElementFormatter converts an item into its text in a set literal.
+type ElementFormatter func(Comparable) string
+
+// A SyntaxError describes a set literal that couldn't be parsed. Line and Column count from 1, with columns in bytes.
+type SyntaxError struct {
+	Line   int
+	Column int
+	Msg    string
+	Err    error // set when an ElementParser failed
+}
+
+func (a *SyntaxError) Error() string {
+	if a.Err != nil {
+		return fmt.Sprintf("unordered: %v:%v: %v: %v", a.Line, a.Column, a.Msg, a.Err)
+	}
+	return fmt.Sprintf("unordered: %v:%v: %v", a.Line, a.Column, a.Msg)
+}
+
+func (a *SyntaxError) Unwrap() error {
+	return a.Err
+}
+
+// Parses a set literal, converting each item with the element parser. Errors are *SyntaxError with the line and column of the problem. The text format of a set is a brace enclosed, comma separated list of items:
+//     {(1,2), (3,4), (3,4)}
+//     {"a", "b"}
+//     {}
+// Items are literals of the element type: a number, a double quoted Go string, a parenthesized tuple, or any text without top-level commas and braces. Whitespace and newlines between items are ignored, and a trailing comma is allowed. The set may be prefixed with a registered type name, as written by MarshalText:
+//     coord{(1,2), (3,4)}
+func Parse(text string, with ElementParser) (EqualSet, error) {
+	if asserting {
+		if with == nil {
+			panic("unordered: nil ElementParser")
+		}
+	}
+	_, items, err := parseLiteral(text)
+	if err != nil {
+		return nil, err
+	}
+	out := make(EqualSet, 0, len(items))
+	for _, item := range items {
+		c, err := with(item.text)
+		if err != nil {
+			return nil, item.errorf(err, "invalid item %v", item.text)
+		}
+		if c == nil {
+			return nil, item.errorf(nil, "item %v parsed to nil", item.text)
+		}
+		if (len(out) > 0) && (reflect.TypeOf(c) != reflect.TypeOf(out[0])) {
+			return nil, item.errorf(nil, "item %v type %v doesn't match set type %v", item.text, reflect.TypeOf(c), reflect.TypeOf(out[0]))
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// Writes the set literal of the set, converting each item with the element formatter. A nil formatter uses the item's MarshalText if it implements encoding.TextMarshaler, otherwise fmt.Sprint.
+func Format(w io.Writer, the EqualSet, with ElementFormatter) error {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil set")
+		}
+	}
+	if with == nil {
+		with = defaultFormatter
+	}
+	_, err := io.WriteString(w, formatLiteral("", the, with))
+	return err
+}
+
+func defaultFormatter(c Comparable) string {
+	if m, ok := c.(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		if err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(c)
+}
+
+func formatLiteral(name string, the EqualSet, with ElementFormatter) string {
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('{')
+	for i, item := range the {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(with(item))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// Parses integer items like -12, converting them into items with the function.
+func IntParser(to func(int64) Comparable) ElementParser {
+	return func(text string) (Comparable, error) {
+		i, err := strconv.ParseInt(text, 0, 64)
+		if err != nil {
+			return nil, err
+		}
+		return to(i), nil
+	}
+}
+
+// Parses floating point items like 1.5e3, converting them into items with the function.
+func FloatParser(to func(float64) Comparable) ElementParser {
+	return func(text string) (Comparable, error) {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, err
+		}
+		return to(f), nil
+	}
+}
+
+// Parses double quoted Go string items like "a\tb", converting them into items with the function.
+func StringParser(to func(string) Comparable) ElementParser {
+	return func(text string) (Comparable, error) {
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("not a quoted string")
+		}
+		return to(s), nil
+	}
+}
+
+// Parses parenthesized tuple items like (1, "a"), giving the trimmed text of each field to the function. Fields are separated by top-level commas and may be quoted strings or nested tuples.
+func TupleParser(to func(fields []string) (Comparable, error)) ElementParser {
+	return func(text string) (Comparable, error) {
+		if (len(text) < 2) || (text[0] != '(') || (text[len(text)-1] != ')') {
+			return nil, fmt.Errorf("not a parenthesized tuple")
+		}
+		inner := text[1 : len(text)-1]
+		fields := make([]string, 0, 4)
+		if strings.TrimSpace(inner) == "" {
+			return to(fields)
+		}
+		start := 0
+		for {
+			end, err := scanItem(inner, start, ',')
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, strings.TrimSpace(inner[start:end]))
+			if end == len(inner) {
+				break
+			}
+			start = end + 1
+		}
+		return to(fields)
+	}
+}
+
+// Parses tuples of integers like (1, -2). If n is more than zero then tuples must have exactly n fields.
+func IntTupleParser(n int, to func([]int64) Comparable) ElementParser {
+	return TupleParser(func(fields []string) (Comparable, error) {
+		if (n > 0) && (len(fields) != n) {
+			return nil, fmt.Errorf("%v fields, expected %v", len(fields), n)
+		}
+		ints := make([]int64, len(fields))
+		for i, f := range fields {
+			var err error
+			ints[i], err = strconv.ParseInt(f, 0, 64)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return to(ints), nil
+	})
+}
+
+// Formats items with strconv.Quote of fmt.Sprint, matching StringParser.
+func QuotedFormatter(c Comparable) string {
+	return strconv.Quote(fmt.Sprint(c))
+}
+
+type literalItem struct {
+	text   string
+	line   int
+	column int
+}
+
+func (an literalItem) errorf(err error, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{
+		Line:   an.line,
+		Column: an.column,
+		Msg:    fmt.Sprintf(format, args...),
+		Err:    err,
+	}
+}
+
+// Splits a set literal into its optional type name and the text of its items.
+func parseLiteral(text string) (string, []literalItem, error) {
+	fail := func(at int, format string, args ...interface{}) error {
+		line, column := position(text, at)
+		return &SyntaxError{Line: line, Column: column, Msg: fmt.Sprintf(format, args...)}
+	}
+	i := skipSpace(text, 0)
+	nameStart := i
+	for (i < len(text)) && (text[i] != '{') && (isSpace(text[i]) == false) {
+		r := rune(text[i])
+		if (r != '_') && (r != '-') && (r != '.') && (unicode.IsLetter(r) == false) && (unicode.IsDigit(r) == false) {
+			return "", nil, fail(i, "unexpected %q before {", text[i])
+		}
+		i++
+	}
+	name := text[nameStart:i]
+	i = skipSpace(text, i)
+	if (i >= len(text)) || (text[i] != '{') {
+		return "", nil, fail(i, "expected {")
+	}
+	i++
+	items := make([]literalItem, 0, 8)
+	for {
+		i = skipSpace(text, i)
+		if i >= len(text) {
+			return "", nil, fail(i, "expected }")
+		}
+		if text[i] == '}' {
+			i++
+			break
+		}
+		end, err := scanItem(text, i, '}')
+		if err != nil {
+			return "", nil, fail(end, "%v", err)
+		}
+		if end >= len(text) {
+			return "", nil, fail(end, "expected }")
+		}
+		itemText := strings.TrimRightFunc(text[i:end], unicode.IsSpace)
+		if itemText == "" {
+			return "", nil, fail(i, "expected item")
+		}
+		line, column := position(text, i)
+		items = append(items, literalItem{text: itemText, line: line, column: column})
+		i = end
+		if text[i] == ',' {
+			i++
+		}
+	}
+	i = skipSpace(text, i)
+	if i != len(text) {
+		return "", nil, fail(i, "unexpected %q after }", text[i])
+	}
+	return name, items, nil
+}
+
+// Returns the index of the top-level comma or closing byte that ends the item starting at the index, or the length of the text if neither is found. Quoted strings and parentheses are skipped.
+func scanItem(text string, start int, closing byte) (int, error) {
+	depth := 0
+	i := start
+	for i < len(text) {
+		switch c := text[i]; c {
+		case '"', '`':
+			end := i + 1
+			for end < len(text) {
+				if (c == '"') && (text[end] == '\\') {
+					end += 2
+					continue
+				}
+				if text[end] == c {
+					break
+				}
+				end++
+			}
+			if end >= len(text) {
+				return i, fmt.Errorf("unterminated string")
+			}
+			i = end
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i, fmt.Errorf("unexpected )")
+			}
+			depth--
+		case '{':
+			return i, fmt.Errorf("unexpected {")
+		case ',':
+			if depth == 0 {
+				return i, nil
+			}
+		case closing:
+			if depth == 0 {
+				return i, nil
+			}
+			return i, fmt.Errorf("unexpected %q in tuple", c)
+		}
+		i++
+	}
+	if depth > 0 {
+		return i, fmt.Errorf("expected )")
+	}
+	return i, nil
+}
+
+func isSpace(c byte) bool {
+	return (c == ' ') || (c == '\t') || (c == '\n') || (c == '\r')
+}
+
+func skipSpace(text string, i int) int {
+	for (i < len(text)) && isSpace(text[i]) {
+		i++
+	}
+	return i
+}
+
+func position(text string, at int) (int, int) {
+	if at > len(text) {
+		at = len(text)
+	}
+	line := 1 + strings.Count(text[:at], "\n")
+	return line, at - strings.LastIndexByte(text[:at], '\n')
+}
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Encodes the set as a set literal prefixed with the registered type name, like coord{(1,2), (3,4)}. Items must implement encoding.TextMarshaler and encode as a single literal: a number, a quoted string, or a parenthesized tuple.
+func (an EqualSet) MarshalText() ([]byte, error) {
+	s := an.items()
+	name, err := s.registeredName()
+	if err != nil {
+		return nil, err
+	}
+	if (len(s) > 0) && (s.typeof().Implements(textMarshalerType) == false) {
+		return nil, fmt.Errorf("unordered: type %v doesn't implement encoding.TextMarshaler", s.typeof())
+	}
+	var failed error
+	out := formatLiteral(name, an, func(c Comparable) string {
+		text, err := c.(encoding.TextMarshaler).MarshalText()
+		if (err != nil) && (failed == nil) {
+			failed = err
+		}
+		return string(text)
+	})
+	if failed != nil {
+		return nil, failed
+	}
+	return []byte(out), nil
+}
+
+// Decodes a set literal written by MarshalText, replacing the receiver's items. The type name must be registered, and a pointer to the type must implement encoding.TextUnmarshaler. An empty set may omit the name.
+func (an *EqualSet) UnmarshalText(text []byte) error {
+	name, items, err := parseLiteral(string(text))
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		*an = EqualSet{}
+		return nil
+	}
+	if name == "" {
+		return &SyntaxError{Line: 1, Column: 1, Msg: "set literal has no type name"}
+	}
+	t, err := registeredType(name)
+	if err != nil {
+		return err
+	}
+	err = comparableType(t)
+	if err != nil {
+		return err
+	}
+	if (t.Kind() != reflect.Ptr) && (reflect.PtrTo(t).Implements(textUnmarshalerType) == false) {
+		return fmt.Errorf("unordered: type %v doesn't implement encoding.TextUnmarshaler", t)
+	}
+	out := make(EqualSet, len(items))
+	for i, item := range items {
+		ptr, get := newItem(t)
+		u, ok := ptr.(encoding.TextUnmarshaler)
+		if ok == false {
+			return fmt.Errorf("unordered: type %v doesn't implement encoding.TextUnmarshaler", t)
+		}
+		err = u.UnmarshalText([]byte(item.text))
+		if err != nil {
+			return item.errorf(err, "invalid %v %v", name, item.text)
+		}
+		out[i] = get().(Comparable)
+	}
+	*an = out
+	return nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// Pair is like Coordinate but satisfies encoding.TextMarshaler for set literals.
+type Pair struct {
+	X, Y int
+}
+
+func (the Pair) Equal(to Comparable) bool {
+	return the == to.(Pair)
+}
+
+func (the Pair) MarshalText() ([]byte, error) {
+	return []byte(coordinateFormatter(Coordinate(the))), nil
+}
+
+func (the *Pair) UnmarshalText(text []byte) error {
+	c, err := coordinateParser(string(text))
+	if err != nil {
+		return err
+	}
+	*the = Pair(c.(Coordinate))
+	return nil
+}
+
+func init() {
+	RegisterType("pair", Pair{})
+}
+
+var coordinateParser = IntTupleParser(2, func(fields []int64) Comparable {
+	return Coordinate{int(fields[0]), int(fields[1])}
+})
+
+func coordinateFormatter(c Comparable) string {
+	the := c.(Coordinate)
+	return fmt.Sprintf("(%v,%v)", the.X, the.Y)
+}
+
+type ParseCase struct {
+	Text string
+	ElementParser
+	Out EqualSet
+}
+
+var ParseCases = []ParseCase{
+	{
+		Text:          "{(1,2), (3,4), (3,4)}",
+		ElementParser: coordinateParser,
+		Out:           EqualSet{Coordinate{3, 4}, Coordinate{1, 2}, Coordinate{3, 4}},
+	},
+	{
+		Text:          `{"a", "b", "a, b}"}`,
+		ElementParser: StringParser(func(s string) Comparable { return String(s) }),
+		Out:           EqualSet{String("b"), String("a"), String("a, b}")},
+	},
+	{
+		Text:          " {\n\t1,\n\t-2,\n\t0x10,\n}\n",
+		ElementParser: IntParser(func(i int64) Comparable { return Int(i) }),
+		Out:           EqualSet{Int(1), Int(16), Int(-2)},
+	},
+	{
+		Text:          "{1.5, 2.5e1}",
+		ElementParser: FloatParser(func(f float64) Comparable { return Int(f * 2) }),
+		Out:           EqualSet{Int(3), Int(50)},
+	},
+	{
+		Text:          "{}",
+		ElementParser: coordinateParser,
+		Out:           EqualSet{},
+	},
+}
+
+func TestParse(t *testing.T) {
+	for i, c := range ParseCases {
+		out, err := Parse(c.Text, c.ElementParser)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		if out.Equal(c.Out) == false {
+			t.Fatalf("%v: %v not equal to %v", i, out, c.Out)
+		}
+	}
+}
+
+type ParseErrorCase struct {
+	Text   string
+	Line   int
+	Column int
+}
+
+var ParseErrorCases = []ParseErrorCase{
+	{Text: "(1,2)", Line: 1, Column: 1},
+	{Text: "{(1,2), (3,4)", Line: 1, Column: 14},
+	{Text: "{(1,2),\n (3,4,5)}", Line: 2, Column: 2},
+	{Text: "{(1,2),, (3,4)}", Line: 1, Column: 8},
+	{Text: "{(1,2)} x", Line: 1, Column: 9},
+	{Text: "{(1,2), (3,4}", Line: 1, Column: 13},
+	{Text: "{(1,a)}", Line: 1, Column: 2},
+}
+
+func TestParseErrors(t *testing.T) {
+	for i, c := range ParseErrorCases {
+		_, err := Parse(c.Text, coordinateParser)
+		var syntax *SyntaxError
+		if errors.As(err, &syntax) == false {
+			t.Fatalf("%v: unexpected error %v", i, err)
+		}
+		if (syntax.Line != c.Line) || (syntax.Column != c.Column) {
+			t.Fatalf("%v: error at %v:%v, expected %v:%v (%v)", i, syntax.Line, syntax.Column, c.Line, c.Column, err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for i, c := range ParseCases[:2] {
+		var b bytes.Buffer
+		with := ElementFormatter(coordinateFormatter)
+		if i == 1 {
+			with = QuotedFormatter
+		}
+		err := Format(&b, c.Out, with)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := Parse(b.String(), c.ElementParser)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		if out.Equal(c.Out) == false {
+			t.Fatalf("%v: %v not equal to %v", i, out, c.Out)
+		}
+	}
+	var b bytes.Buffer
+	err := Format(&b, EqualSet{Int(1), Int(2)}, nil)
+	if (err != nil) || (b.String() != "{1, 2}") {
+		t.Fatalf("unexpected default format %v", b.String())
+	}
+	if s := strconv.Quote("a"); QuotedFormatter(String("a")) != s {
+		t.Fatal("QuotedFormatter failed")
+	}
+}
+
+func TestTextFlag(t *testing.T) {
+	var set EqualSet
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.TextVar(&set, "coords", EqualSet{}, "coordinates")
+	err := flags.Parse([]string{"-coords", "pair{(1,2), (1,2), (0,-1)}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Equal(EqualSet{Pair{1, 2}, Pair{0, -1}, Pair{1, 2}}) == false {
+		t.Fatalf("unexpected set %v", set)
+	}
+	text, err := set.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "pair{(1,2), (1,2), (0,-1)}" {
+		t.Fatalf("unexpected text %v", string(text))
+	}
+	err = set.UnmarshalText([]byte("{(1,2)}"))
+	if err == nil {
+		t.Fatal("set literal without type name decoded")
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The TransactionMode decides whether a Transaction checks for changes made by others before it commits.
+type TransactionMode int
+
+const (
+	// Commit applies the pending changes to the set as it is at commit time, whatever was committed since Begin.
+	LastWriterWins TransactionMode = iota
+	// Commit fails with ErrConflict if an item the transaction read or changed was changed in the set after Begin.
+	DetectConflicts
+)
+
+var (
+	ErrConflict        = errors.New("unordered: transaction conflicts with a change made after it began")
+	ErrTransactionDone = errors.New("unordered: transaction already committed or rolled back")
+)
+
+// A Transaction collects Add, Remove, and RemoveAll changes to an ObservableSet that are applied together by Commit or discarded by Rollback. Reads from the transaction see the set with the pending changes applied. Other readers of the set don't see pending changes, and subscribers receive the changes only when committed.
+//
+// A Transaction is used by one goroutine. Many transactions can be open on the same set. While a DetectConflicts transaction is open the set logs the items changed by others, so one that isn't committed must be rolled back.
+type Transaction struct {
+	of      *ObservableSet
+	mode    TransactionMode
+	ops     []setOp
+	begin   uint64
+	read    EqualSet // items read or changed, for conflict detection
+	readAll bool
+	done    bool
+}
+
+// A modification records the version of the set when an item was changed.
+type modification struct {
+	version uint64
+	item    Comparable
+}
+
+// Starts a transaction on the set. The mode defaults to LastWriterWins.
+func (an *ObservableSet) Begin(mode ...TransactionMode) *Transaction {
+	m := LastWriterWins
+	if len(mode) > 0 {
+		if asserting {
+			if len(mode) > 1 {
+				panic("unordered: more than one TransactionMode")
+			}
+		}
+		m = mode[0]
+	}
+	t := &Transaction{
+		of:   an,
+		mode: m,
+		ops:  make([]setOp, 0, 8),
+		read: make(EqualSet, 0),
+	}
+	an.mutex.Lock()
+	t.begin = an.version
+	if m == DetectConflicts {
+		an.checked[t] = struct{}{}
+	}
+	an.mutex.Unlock()
+	return t
+}
+
+// Must be called with the mutex held. The version is increased and, while transactions are checking for conflicts, the item is logged.
+func (an *ObservableSet) modified(the Comparable) {
+	an.version++
+	if len(an.checked) > 0 {
+		an.log = append(an.log, modification{version: an.version, item: the})
+	}
+}
+
+// Must be called with the mutex held. Log entries older than every checking transaction are dropped.
+func (an *ObservableSet) end(the *Transaction) {
+	if the.mode != DetectConflicts {
+		return
+	}
+	delete(an.checked, the)
+	oldest := an.version
+	for t := range an.checked {
+		if t.begin < oldest {
+			oldest = t.begin
+		}
+	}
+	i := 0
+	for (i < len(an.log)) && (an.log[i].version <= oldest) {
+		i++
+	}
+	an.log = append(an.log[:0], an.log[i:]...)
+}
+
+// Adds an item to the transaction's view of the set.
+func (a *Transaction) Add(the Comparable) {
+	a.record(opAdd, the)
+}
+
+// Removes one matching item from the transaction's view of the set.
+func (a *Transaction) Remove(the Comparable) {
+	a.record(opRemove, the)
+}
+
+// Removes all matching items from the transaction's view of the set.
+func (a *Transaction) RemoveAll(the Comparable) {
+	a.record(opRemoveAll, the)
+}
+
+// If the transaction's view of the set has the item then true is returned.
+func (a *Transaction) Has(the Comparable) bool {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	a.check()
+	a.touch(the)
+	a.of.mutex.Lock()
+	count := 0
+	for _, item := range a.of.set {
+		if item.Equal(the) {
+			count++
+		}
+	}
+	a.of.mutex.Unlock()
+	for _, op := range a.ops {
+		if op.item.Equal(the) == false {
+			continue
+		}
+		switch op.kind {
+		case opAdd:
+			count++
+		case opRemove:
+			if count > 0 {
+				count--
+			}
+		case opRemoveAll:
+			count = 0
+		}
+	}
+	return count > 0
+}
+
+// Returns a copy of the transaction's view of the set.
+func (a *Transaction) Set() EqualSet {
+	a.check()
+	a.readAll = true
+	out := a.of.Set()
+	for _, op := range a.ops {
+		switch op.kind {
+		case opAdd:
+			out = append(out, op.item)
+		case opRemove:
+			out, _ = removeOne(out, op.item)
+		case opRemoveAll:
+			out, _ = removeAll(out, op.item)
+		}
+	}
+	return out
+}
+
+// Applies the pending changes to the set at once and sends them to subscribers. With DetectConflicts ErrConflict is returned and nothing is applied if an item the transaction used was changed after Begin. An error is also returned and nothing applied if an item's type doesn't match the set's. The transaction is done after Commit, even if it fails.
+func (a *Transaction) Commit() error {
+	if a.done {
+		return ErrTransactionDone
+	}
+	a.done = true
+	return a.of.run(a.ops, func() error {
+		defer a.of.end(a)
+		// a mistyped item would panic partway through applying the changes
+		var t itemType
+		if len(a.of.set) > 0 {
+			t.check(a.of.set[0])
+		}
+		for _, op := range a.ops {
+			err := t.check(op.item)
+			if err != nil {
+				return fmt.Errorf("unordered: %w", err)
+			}
+		}
+		if a.mode != DetectConflicts {
+			return nil
+		}
+		for _, m := range a.of.log {
+			if m.version <= a.begin {
+				continue
+			}
+			if a.readAll || a.read.Has(m.item) {
+				return ErrConflict
+			}
+		}
+		return nil
+	})
+}
+
+// Discards the pending changes. The transaction is done after Rollback.
+func (a *Transaction) Rollback() error {
+	if a.done {
+		return ErrTransactionDone
+	}
+	a.done = true
+	a.of.mutex.Lock()
+	a.of.end(a)
+	a.of.mutex.Unlock()
+	return nil
+}
+
+func (a *Transaction) record(kind setOpKind, the Comparable) {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	a.check()
+	a.touch(the)
+	a.ops = append(a.ops, setOp{kind, the})
+}
+
+func (a *Transaction) touch(the Comparable) {
+	if (a.mode == DetectConflicts) && (a.read.Has(the) == false) {
+		a.read = append(a.read, the)
+	}
+}
+
+func (a *Transaction) check() {
+	if asserting {
+		if a.done {
+			panic("unordered: transaction used after Commit or Rollback")
+		}
+	}
+}
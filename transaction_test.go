@@ -0,0 +1,131 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"testing"
+)
+
+func TestTransaction(t *testing.T) {
+	set := NewObservableSet(EqualSet{Int(1), Int(2)})
+	changes := make([]Change, 0, 4)
+	set.Subscribe(func(c Change) {
+		changes = append(changes, c)
+	})
+	tx := set.Begin()
+	tx.Add(Int(3))
+	tx.Remove(Int(1))
+	tx.Add(Int(2))
+	if (tx.Has(Int(3)) == false) || tx.Has(Int(1)) {
+		t.Fatal("transaction doesn't see pending changes")
+	}
+	if tx.Set().Equal(EqualSet{Int(2), Int(2), Int(3)}) == false {
+		t.Fatalf("unexpected transaction view %v", tx.Set())
+	}
+	if set.Has(Int(3)) || (len(changes) != 0) {
+		t.Fatal("pending change visible outside the transaction")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if set.Set().Equal(EqualSet{Int(2), Int(2), Int(3)}) == false {
+		t.Fatalf("unexpected set %v", set.Set())
+	}
+	if len(changes) != 3 {
+		t.Fatalf("%v changes sent, expected 3", len(changes))
+	}
+	if tx.Commit() != ErrTransactionDone {
+		t.Fatal("committed twice")
+	}
+	tx = set.Begin()
+	tx.RemoveAll(Int(2))
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if set.Set().Equal(EqualSet{Int(2), Int(2), Int(3)}) == false {
+		t.Fatal("rolled back change applied")
+	}
+}
+
+func TestTransactionConflict(t *testing.T) {
+	set := NewObservableSet(EqualSet{Int(1), Int(2)})
+	a := set.Begin(DetectConflicts)
+	b := set.Begin(DetectConflicts)
+	c := set.Begin()
+	if a.Has(Int(1)) == false {
+		t.Fatal("doesn't have 1")
+	}
+	a.Add(Int(4))
+	b.Remove(Int(1))
+	b.Add(Int(5))
+	c.RemoveAll(Int(4))
+	if err := b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Commit(); err != ErrConflict {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if set.Has(Int(4)) {
+		t.Fatal("conflicting transaction applied")
+	}
+	d := set.Begin(DetectConflicts)
+	d.Add(Int(6))
+	set.Add(Int(7))
+	if err := d.Commit(); err != nil {
+		t.Fatalf("unrelated change conflicted: %v", err)
+	}
+	if err := c.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	e := set.Begin(DetectConflicts)
+	e.Set()
+	set.Remove(Int(7))
+	if err := e.Commit(); err != ErrConflict {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if set.Set().Equal(EqualSet{Int(2), Int(5), Int(6)}) == false {
+		t.Fatalf("unexpected set %v", set.Set())
+	}
+	if (len(set.log) != 0) || (len(set.checked) != 0) {
+		t.Fatal("conflict log not emptied")
+	}
+}
+
+func TestTransactionMistyped(t *testing.T) {
+	set := NewObservableSet(EqualSet{Int(1), Int(2)})
+	changes := 0
+	set.Subscribe(func(c Change) {
+		changes++
+	})
+	tx := set.Begin()
+	tx.Add(Int(3))
+	tx.Remove(Int(1))
+	tx.Add(Coordinate{1, 1})
+	if tx.Commit() == nil {
+		t.Fatal("committed a mistyped item")
+	}
+	if (set.Set().Equal(EqualSet{Int(1), Int(2)}) == false) || (changes != 0) {
+		t.Fatalf("part of a failed transaction applied, set %v", set.Set())
+	}
+}
+
+func TestTransactionRollbackTrimsLog(t *testing.T) {
+	set := NewObservableSet(EqualSet{Int(1)})
+	a := set.Begin(DetectConflicts)
+	set.Add(Int(2))
+	set.Add(Int(3))
+	if len(set.log) != 2 {
+		t.Fatalf("%v changes logged, expected 2", len(set.log))
+	}
+	a.Rollback()
+	set.Add(Int(4))
+	if (len(set.log) != 0) || (len(set.checked) != 0) {
+		t.Fatal("conflict log kept after rollback")
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A UniqueSet holds at most one of each item, so callers don't need EqualSet.Reduce. Unlike EqualSet the Add and Remove methods modify the receiver. Hashable items are found in a hash bucket, other items by comparing against every non-hashable item. The zero value is an empty set in IgnoreDuplicates mode.
+type UniqueSet struct {
+	index index
+	count int
+	mode  DuplicateMode
+}
+
+// The DuplicateMode decides what UniqueSet.Add does with an item the set already has.
+type DuplicateMode int
+
+const (
+	// Adding an item the set has does nothing.
+	IgnoreDuplicates DuplicateMode = iota
+	// Adding an item the set has returns an error wrapping ErrDuplicate.
+	ReportDuplicates
+)
+
+// Returned by UniqueSet.Add in ReportDuplicates mode, wrapped with the item.
+var ErrDuplicate = errors.New("unordered: duplicate item")
+
+// Creates a UniqueSet with one of each item of the set. The mode defaults to IgnoreDuplicates, and duplicates in the argument are always ignored.
+func NewUniqueSet(from EqualSet, mode ...DuplicateMode) *UniqueSet {
+	if asserting {
+		if len(mode) > 1 {
+			panic("unordered: more than one duplicate mode")
+		}
+	}
+	out := &UniqueSet{}
+	for _, item := range from {
+		out.Add(item)
+	}
+	if len(mode) == 1 {
+		out.mode = mode[0]
+	}
+	return out
+}
+
+// Adds the item if the set doesn't have it. If the set has it then nil is returned in IgnoreDuplicates mode, or an error wrapping ErrDuplicate in ReportDuplicates mode.
+func (a *UniqueSet) Add(the Comparable) error {
+	if asserting {
+		if the == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	if a.index.has(the) {
+		if a.mode == ReportDuplicates {
+			return fmt.Errorf("%w %v", ErrDuplicate, the)
+		}
+		return nil
+	}
+	a.index.add(the)
+	a.count++
+	return nil
+}
+
+// Removes the item and reports if the set had it.
+func (a *UniqueSet) Remove(the Comparable) bool {
+	if a.index.remove(the) {
+		a.count--
+		return true
+	}
+	return false
+}
+
+// If the set has the item then true is returned.
+func (a *UniqueSet) Has(the Comparable) bool {
+	return a.index.has(the)
+}
+
+// Returns the count of items in the set.
+func (a *UniqueSet) Len() int {
+	return a.count
+}
+
+// Returns the items as an EqualSet, in no particular order.
+func (a *UniqueSet) EqualSet() EqualSet {
+	return a.index.items()
+}
+
+// Combines the items of the receiver and the argument sets into a new UniqueSet with the receiver's mode. Duplicates are removed.
+func (a *UniqueSet) Combine(with ...EqualSet) *UniqueSet {
+	out := NewUniqueSet(a.EqualSet(), a.mode)
+	for _, set := range with {
+		for _, item := range set {
+			if out.index.has(item) {
+				continue
+			}
+			out.index.add(item)
+			out.count++
+		}
+	}
+	return out
+}
+
+// If both sets have the same items then true is returned.
+func (a *UniqueSet) Equal(to *UniqueSet) bool {
+	if a.count != to.count {
+		return false
+	}
+	for _, item := range a.EqualSet() {
+		if to.Has(item) == false {
+			return false
+		}
+	}
+	return true
+}
+
+// Provides a set of the items not in both sets, with the receiver's mode.
+func (a *UniqueSet) Diff(from *UniqueSet) *UniqueSet {
+	out := NewUniqueSet(nil, a.mode)
+	for _, item := range a.EqualSet() {
+		if from.Has(item) == false {
+			out.Add(item)
+		}
+	}
+	for _, item := range from.EqualSet() {
+		if a.Has(item) == false {
+			out.Add(item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUniqueSet(t *testing.T) {
+	for _, item := range []func(int) Comparable{
+		func(i int) Comparable { return Int(i) },
+		func(i int) Comparable { return Collider(i) },
+		func(i int) Comparable { return Coordinate{i, 0} },
+	} {
+		from := EqualSet{item(1), item(2), item(2), item(3)}
+		s := NewUniqueSet(from)
+		if (s.Len() != 3) || (s.EqualSet().Equal(from.Reduce()) == false) {
+			t.Fatalf("%T: %v", item(0), s.EqualSet())
+		}
+		if (s.Add(item(1)) != nil) || (s.Len() != 3) {
+			t.Fatalf("%T: duplicate added", item(0))
+		}
+		if (s.Add(item(4)) != nil) || (s.Has(item(4)) == false) || (s.Len() != 4) {
+			t.Fatalf("%T: not added", item(0))
+		}
+		if (s.Remove(item(2)) == false) || s.Has(item(2)) || s.Remove(item(2)) || (s.Len() != 3) {
+			t.Fatalf("%T: Remove", item(0))
+		}
+		c := s.Combine(EqualSet{item(4), item(5), item(5)}, EqualSet{item(1), item(6)})
+		if c.EqualSet().Equal(EqualSet{item(1), item(3), item(4), item(5), item(6)}) == false {
+			t.Fatalf("%T: Combine %v", item(0), c.EqualSet())
+		}
+		if s.Len() != 3 {
+			t.Fatalf("%T: Combine modified the receiver", item(0))
+		}
+		if c.Diff(s).Equal(NewUniqueSet(EqualSet{item(5), item(6)})) == false {
+			t.Fatalf("%T: Diff %v", item(0), c.Diff(s).EqualSet())
+		}
+		if (s.Equal(NewUniqueSet(EqualSet{item(4), item(3), item(1), item(1)})) == false) || s.Equal(c) {
+			t.Fatalf("%T: Equal", item(0))
+		}
+	}
+}
+
+func TestUniqueSetReportDuplicates(t *testing.T) {
+	s := NewUniqueSet(EqualSet{Int(1), Int(1)}, ReportDuplicates)
+	if s.Len() != 1 {
+		t.Fatal("duplicates in the argument set not ignored")
+	}
+	err := s.Add(Int(1))
+	if errors.Is(err, ErrDuplicate) == false {
+		t.Fatal(err)
+	}
+	if s.Add(Int(2)) != nil {
+		t.Fatal("new item reported")
+	}
+	if errors.Is(s.Combine(EqualSet{Int(3)}).Add(Int(3)), ErrDuplicate) == false {
+		t.Fatal("Combine didn't keep the mode")
+	}
+}
+
+func TestUniqueSetZeroValue(t *testing.T) {
+	var set UniqueSet
+	if (set.Len() != 0) || set.Has(Int(1)) || set.Remove(Int(1)) || (len(set.EqualSet()) != 0) {
+		t.Fatal("zero value not an empty set")
+	}
+	set.Add(Int(1))
+	set.Add(Int(2))
+	set.Add(Int(1))
+	if (set.Len() != 2) || (set.Has(Int(1)) == false) {
+		t.Fatalf("unexpected set %v", set.EqualSet())
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"errors"
+)
+
+// A VersionedSet is a mutable EqualSet that records every change as a numbered version, so changes can be undone and redone and earlier versions of the set retrieved. A change that leaves the set Equal to the previous version, like removing an item the set doesn't have, doesn't create a version.
+//
+// A VersionedSet is used by one goroutine.
+type VersionedSet struct {
+	retention Retention
+	base      EqualSet // the set before the oldest retained version
+	baseNum   int
+	versions  []Version
+	applied   int // count of versions in effect, less than len(versions) after Undo
+	current   EqualSet
+}
+
+// A Version is the Patch from the previous version of a VersionedSet. A squashed version combines the versions numbered From to Number.
+type Version struct {
+	Number  int
+	From    int
+	Changes Patch
+}
+
+// The Retention of a VersionedSet limits how much history is kept.
+type Retention struct {
+	// The count of versions kept. Zero keeps every version.
+	Keep int
+	// If true then versions beyond Keep are squashed into the oldest kept version instead of dropped, so Undo can still return to the first version in one step.
+	Squash bool
+}
+
+// ErrVersionNotRetained is returned by At for a version that was dropped or squashed by the Retention.
+var ErrVersionNotRetained = errors.New("unordered: version not retained")
+
+// Creates a VersionedSet at version 0 holding a copy of the items of the set.
+func NewVersionedSet(from EqualSet, retention Retention) *VersionedSet {
+	if asserting {
+		if from == nil {
+			panic("unordered: nil arg")
+		}
+		if retention.Keep < 0 {
+			panic("unordered: negative Retention.Keep")
+		}
+	}
+	return &VersionedSet{
+		retention: retention,
+		base:      append(EqualSet{}, from...),
+		versions:  make([]Version, 0, 8),
+		current:   append(EqualSet{}, from...),
+	}
+}
+
+// Adds an item as a new version.
+func (a *VersionedSet) Add(the Comparable) {
+	a.record(Patch{{Kind: Added, Item: the, Count: 1}})
+}
+
+// Removes one matching item as a new version. No version is made if the set doesn't have the item.
+func (a *VersionedSet) Remove(the Comparable) {
+	if a.current.Has(the) {
+		a.record(Patch{{Kind: Removed, Item: the, Count: 1}})
+	}
+}
+
+// Removes all matching items as a new version. No version is made if the set doesn't have the item.
+func (a *VersionedSet) RemoveAll(the Comparable) {
+	if count := countOf(a.current, the); count > 0 {
+		a.record(Patch{{Kind: Removed, Item: the, Count: count}})
+	}
+}
+
+// Replaces the items of the set as a new version. No version is made if the set is Equal to the current items.
+func (a *VersionedSet) Replace(with EqualSet) {
+	if asserting {
+		if with == nil {
+			panic("unordered: nil arg")
+		}
+	}
+	if a.current.Equal(with) {
+		return
+	}
+	a.record(Delta(a.current, with))
+}
+
+// Returns a copy of the items of the current version.
+func (a *VersionedSet) Set() EqualSet {
+	return append(EqualSet{}, a.current...)
+}
+
+// Returns the number of the current version.
+func (a *VersionedSet) Version() int {
+	if a.applied == 0 {
+		return a.baseNum
+	}
+	return a.versions[a.applied-1].Number
+}
+
+// Returns the set to the previous version. False is returned if there's no retained earlier version.
+func (a *VersionedSet) Undo() bool {
+	if a.applied == 0 {
+		return false
+	}
+	a.applied--
+	a.current = a.versions[a.applied].Changes.Invert().apply(a.current)
+	return true
+}
+
+// Reapplies the version undone last. False is returned if there's nothing to redo. Any change made after Undo discards the undone versions.
+func (a *VersionedSet) Redo() bool {
+	if a.applied == len(a.versions) {
+		return false
+	}
+	a.current = a.versions[a.applied].Changes.apply(a.current)
+	a.applied++
+	return true
+}
+
+// Returns the items of the set at the version, which may be an undone version that can still be redone.
+func (a *VersionedSet) At(version int) (EqualSet, error) {
+	out := append(EqualSet{}, a.base...)
+	if version == a.baseNum {
+		return out, nil
+	}
+	for _, v := range a.versions {
+		if (version >= v.From) && (version < v.Number) {
+			return nil, ErrVersionNotRetained
+		}
+		out = v.Changes.apply(out)
+		if v.Number == version {
+			return out, nil
+		}
+	}
+	return nil, ErrVersionNotRetained
+}
+
+// Returns the retained versions, oldest first, including undone versions that can still be redone.
+func (a *VersionedSet) Log() []Version {
+	out := make([]Version, len(a.versions))
+	for i, v := range a.versions {
+		out[i] = Version{
+			Number:  v.Number,
+			From:    v.From,
+			Changes: append(Patch{}, v.Changes...),
+		}
+	}
+	return out
+}
+
+func (a *VersionedSet) record(changes Patch) {
+	n := a.Version() + 1
+	if a.applied < len(a.versions) {
+		// the undone versions are discarded, and their numbers reused
+		a.versions = a.versions[:a.applied]
+	}
+	a.current = changes.apply(a.current)
+	a.versions = append(a.versions, Version{Number: n, From: n, Changes: changes})
+	a.applied++
+	keep := a.retention.Keep
+	if keep == 0 {
+		return
+	}
+	for len(a.versions) > keep {
+		if a.retention.Squash {
+			first, second := a.versions[0], a.versions[1]
+			after := second.Changes.apply(first.Changes.apply(append(EqualSet{}, a.base...)))
+			if after.Equal(a.base) {
+				// the squashed versions cancel out
+				a.baseNum = second.Number
+				a.versions = a.versions[2:]
+				a.applied -= 2
+				continue
+			}
+			merged := Version{Number: second.Number, From: first.From, Changes: Delta(a.base, after)}
+			a.versions = append([]Version{merged}, a.versions[2:]...)
+			a.applied--
+			continue
+		}
+		a.base = a.versions[0].Changes.apply(a.base)
+		a.baseNum = a.versions[0].Number
+		a.versions = a.versions[1:]
+		a.applied--
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2017 Matthew Juran
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package unordered
+
+import (
+	"testing"
+)
+
+func TestVersionedSet(t *testing.T) {
+	set := NewVersionedSet(EqualSet{Coordinate{0, 0}}, Retention{})
+	set.Add(Coordinate{1, 1})
+	set.Add(Coordinate{1, 1})
+	set.Remove(Coordinate{5, 5})
+	set.RemoveAll(Coordinate{1, 1})
+	set.Replace(EqualSet{Coordinate{0, 0}})
+	set.Replace(EqualSet{Coordinate{2, 2}, Coordinate{2, 2}})
+	if set.Version() != 4 {
+		t.Fatalf("version %v, expected 4", set.Version())
+	}
+	expected := []EqualSet{
+		{Coordinate{0, 0}},
+		{Coordinate{0, 0}, Coordinate{1, 1}},
+		{Coordinate{0, 0}, Coordinate{1, 1}, Coordinate{1, 1}},
+		{Coordinate{0, 0}},
+		{Coordinate{2, 2}, Coordinate{2, 2}},
+	}
+	for i, e := range expected {
+		at, err := set.At(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if at.Equal(e) == false {
+			t.Fatalf("version %v is %v, expected %v", i, at, e)
+		}
+	}
+	for i := len(expected) - 2; i >= 0; i-- {
+		if set.Undo() == false {
+			t.Fatalf("%v: Undo failed", i)
+		}
+		if set.Set().Equal(expected[i]) == false {
+			t.Fatalf("Undo to %v is %v", i, set.Set())
+		}
+	}
+	if set.Undo() {
+		t.Fatal("Undo past the first version")
+	}
+	set.Redo()
+	set.Redo()
+	if (set.Version() != 2) || (set.Set().Equal(expected[2]) == false) {
+		t.Fatalf("Redo to version %v is %v", set.Version(), set.Set())
+	}
+	set.Add(Coordinate{3, 3})
+	if set.Redo() {
+		t.Fatal("Redo after a new change")
+	}
+	if log := set.Log(); (len(log) != 3) || (log[2].Changes[0] != Change{Kind: Added, Item: Coordinate{3, 3}, Count: 1}) {
+		t.Fatalf("unexpected log %v", log)
+	}
+}
+
+func TestVersionedSetRetention(t *testing.T) {
+	keep := NewVersionedSet(EqualSet{}, Retention{Keep: 2})
+	squash := NewVersionedSet(EqualSet{}, Retention{Keep: 2, Squash: true})
+	for i := 1; i <= 5; i++ {
+		keep.Add(Int(i))
+		squash.Add(Int(i))
+	}
+	if _, err := keep.At(2); err != ErrVersionNotRetained {
+		t.Fatal("dropped version retrieved")
+	}
+	if at, err := keep.At(3); (err != nil) || (at.Equal(EqualSet{Int(1), Int(2), Int(3)}) == false) {
+		t.Fatalf("unexpected version 3 %v %v", at, err)
+	}
+	if _, err := squash.At(2); err != ErrVersionNotRetained {
+		t.Fatal("squashed version retrieved")
+	}
+	log := squash.Log()
+	if (len(log) != 2) || (log[0].From != 1) || (log[0].Number != 4) {
+		t.Fatalf("unexpected log %v", log)
+	}
+	for keep.Undo() {
+	}
+	for squash.Undo() {
+	}
+	if keep.Set().Equal(EqualSet{Int(1), Int(2), Int(3)}) == false {
+		t.Fatalf("unexpected oldest kept set %v", keep.Set())
+	}
+	if (squash.Set().Equal(EqualSet{}) == false) || (squash.Version() != 0) {
+		t.Fatalf("unexpected oldest squashed set %v", squash.Set())
+	}
+	cancel := NewVersionedSet(EqualSet{}, Retention{Keep: 1, Squash: true})
+	cancel.Add(Int(1))
+	cancel.Remove(Int(1))
+	cancel.Add(Int(2))
+	if log := cancel.Log(); (len(log) != 1) || (log[0].Number != 3) {
+		t.Fatalf("unexpected log %v", log)
+	}
+}